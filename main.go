@@ -2,11 +2,51 @@ package main
 
 import (
 	"fmt"
+	"github.com/alexjwhite-cb/jet/pkg/lexer"
+	"github.com/alexjwhite-cb/jet/pkg/parser"
 	"github.com/alexjwhite-cb/jet/pkg/repl"
+	"github.com/alexjwhite-cb/jet/pkg/resolver"
+	"github.com/alexjwhite-cb/jet/pkg/types"
 	"os"
 )
 
 func main() {
+	if len(os.Args) == 3 && os.Args[1] == "check" {
+		os.Exit(check(os.Args[2]))
+	}
 	fmt.Printf("Welcome to the Jet programming language!\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// check parses, resolves and type checks the file at path without
+// running it, printing any errors and warnings, and returns the status
+// to exit with.
+func check(path string) int {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	errors := p.Errors()
+	var warnings []string
+	if len(errors) == 0 {
+		errors, warnings = resolver.Resolve(program)
+	}
+	if len(errors) == 0 {
+		var typeWarnings []string
+		errors, typeWarnings = types.Check(program)
+		warnings = append(warnings, typeWarnings...)
+	}
+	for _, msg := range errors {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, msg)
+	}
+	for _, msg := range warnings {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s\n", path, msg)
+	}
+	if len(errors) != 0 {
+		return 1
+	}
+	return 0
+}
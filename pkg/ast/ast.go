@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/alexjwhite-cb/jet/pkg/token"
+	"math/big"
 	"strings"
 )
 
@@ -32,6 +33,20 @@ type (
 		Node
 		declNode()
 	}
+
+	// Pattern - match arm patterns implement the Pattern interface.
+	// Patterns test a value and may bind parts of it to names
+	Pattern interface {
+		Node
+		patternNode()
+	}
+
+	// Target - assignment targets implement the Target interface.
+	// A target is a name, or a pattern that unpacks a value into names
+	Target interface {
+		Node
+		targetNode()
+	}
 )
 
 type Program struct {
@@ -57,32 +72,60 @@ type Ident struct {
 	// This is used for token.IDENT tokens
 	Token token.Token
 	Value string
+	// Depth and Slot are set by the resolver when it works out which
+	// variable a name refers to. Depth counts the scopes out from where
+	// the name is used to the one declaring it, and Slot is the name's
+	// position among that scope's declarations, so the evaluator can read
+	// the variable without looking it up. Both stay 0 when the variable
+	// can't be placed, so that it is searched for from the innermost
+	// scope.
+	Depth int
+	Slot  int
 }
 
 func (i *Ident) exprNode()            {}
+func (i *Ident) targetNode()          {}
 func (i *Ident) TokenLiteral() string { return i.Token.Literal }
 func (i *Ident) String() string       { return i.Value }
 
 type IntLiteral struct {
 	Token token.Token
 	Value int64
+	// Big holds the value instead when it does not fit in an int64
+	Big *big.Int
 }
 
 func (i *IntLiteral) exprNode()            {}
 func (i *IntLiteral) TokenLiteral() string { return i.Token.Literal }
 func (i *IntLiteral) String() string       { return i.Token.Literal }
 
-type ValueStmt struct {
+type FloatLiteral struct {
 	Token token.Token
-	Name  *Ident
-	Value Expr
+	Value float64
+}
+
+func (f *FloatLiteral) exprNode()            {}
+func (f *FloatLiteral) TokenLiteral() string { return f.Token.Literal }
+func (f *FloatLiteral) String() string       { return f.Token.Literal }
+
+// ValueStmt is Name = Value. When the value is destructured, as in
+// [a, b] = Value, Target holds the pattern and Name is nil.
+type ValueStmt struct {
+	Token  token.Token
+	Name   *Ident
+	Target Target
+	Value  Expr
 }
 
 func (vs *ValueStmt) stmtNode()            {}
 func (vs *ValueStmt) TokenLiteral() string { return vs.Token.Literal }
 func (vs *ValueStmt) String() string {
 	var out bytes.Buffer
-	out.WriteString(vs.Name.String())
+	if vs.Target != nil {
+		out.WriteString(vs.Target.String())
+	} else {
+		out.WriteString(vs.Name.String())
+	}
 	out.WriteString(" = ")
 	if vs.Value != nil {
 		out.WriteString(vs.Value.String())
@@ -90,6 +133,19 @@ func (vs *ValueStmt) String() string {
 	return out.String()
 }
 
+// PropertyStmt sets a property of an object, Target = Value.
+type PropertyStmt struct {
+	Token  token.Token
+	Target *MemberExpression
+	Value  Expr
+}
+
+func (ps *PropertyStmt) stmtNode()            {}
+func (ps *PropertyStmt) TokenLiteral() string { return ps.Token.Literal }
+func (ps *PropertyStmt) String() string {
+	return ps.Target.Left.String() + "." + ps.Target.Name.String() + " = " + ps.Value.String()
+}
+
 type ExpressionStmt struct {
 	Token      token.Token
 	Expression Expr
@@ -206,32 +262,89 @@ func (bs *BlockStatement) String() string {
 	return out.String()
 }
 
+// FuncLiteral is a method. Name is set for a declaration such as
+// meth add: x, y {}, and nil for an anonymous method. Defaults lines up
+// with Parameters and holds nil for each parameter without a default.
+// Patterns lines up with Parameters too, and holds the pattern of each
+// destructured parameter, whose Parameters entry is a placeholder
+// named after the pattern.
 type FuncLiteral struct {
 	Token      token.Token
+	Name       *Ident
 	Parameters []*Ident
+	Defaults   []Expr
+	Patterns   []Target
+	// Types lines up with Parameters, holding the type each parameter
+	// is annotated with, or nil
+	Types      []*Ident
+	ReturnType *Ident
 	Body       *BlockStatement
+	// IsGenerator is set when Body yields
+	IsGenerator bool
 }
 
 func (fl *FuncLiteral) exprNode()            {}
 func (fl *FuncLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FuncLiteral) String() string {
 	var out bytes.Buffer
-	var params []string
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString(fl.TokenLiteral())
+	if fl.Name != nil {
+		out.WriteString(" ")
+		out.WriteString(fl.Name.String())
+	}
+	params := FormatTypedParameters(fl.Parameters, fl.Types, fl.Defaults)
+	if fl.Body == nil {
+		// A method a descriptor requires, declared without a body
+		if params != "" {
+			out.WriteString(": " + params)
+		}
+		if fl.ReturnType != nil {
+			out.WriteString(" -> " + fl.ReturnType.String())
+		}
+		return out.String()
+	}
 	out.WriteString(": ")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(params)
+	if fl.ReturnType != nil {
+		if params != "" {
+			out.WriteString(" ")
+		}
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
 
+// FormatParameters writes a parameter list as it appears in source,
+// including any default values.
+func FormatParameters(params []*Ident, defaults []Expr) string {
+	return FormatTypedParameters(params, nil, defaults)
+}
+
+// FormatTypedParameters writes a parameter list like FormatParameters,
+// including the type annotations in types.
+func FormatTypedParameters(params []*Ident, types []*Ident, defaults []Expr) string {
+	var out []string
+	for i, p := range params {
+		param := p.String()
+		if i < len(types) && types[i] != nil {
+			param += " " + types[i].String()
+		}
+		if i < len(defaults) && defaults[i] != nil {
+			param += " = " + defaults[i].String()
+		}
+		out = append(out, param)
+	}
+	return strings.Join(out, ", ")
+}
+
+// CallExpression is Function(Args). Names lines up with Args and holds
+// the parameter name of each named argument, or nil for a positional one.
 type CallExpression struct {
 	Token    token.Token
 	Function Expr
 	Args     []Expr
+	Names    []*Ident
 }
 
 func (ce *CallExpression) exprNode()            {}
@@ -239,8 +352,12 @@ func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	var args []string
-	for _, a := range ce.Args {
-		args = append(args, a.String())
+	for i, a := range ce.Args {
+		if i < len(ce.Names) && ce.Names[i] != nil {
+			args = append(args, ce.Names[i].String()+": "+a.String())
+		} else {
+			args = append(args, a.String())
+		}
 	}
 
 	out.WriteString(ce.Function.String())
@@ -296,10 +413,13 @@ func (a *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// IndexExpression is Left[Index], or Left?[Index] when Optional, which
+// gives null instead of indexing when Left is null.
 type IndexExpression struct {
-	Token token.Token
-	Left  Expr
-	Index Expr
+	Token    token.Token
+	Left     Expr
+	Index    Expr
+	Optional bool
 }
 
 func (ie *IndexExpression) exprNode()            {}
@@ -308,15 +428,455 @@ func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
 	return out.String()
 }
 
+// MemberExpression is Left.Name, or Left?.Name when Optional, which
+// gives null instead of reading Name when Left is null.
+type MemberExpression struct {
+	Token    token.Token
+	Left     Expr
+	Name     *Ident
+	Optional bool
+}
+
+func (me *MemberExpression) exprNode()            {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	op := "."
+	if me.Optional {
+		op = "?."
+	}
+	return "(" + me.Left.String() + op + me.Name.String() + ")"
+}
+
+// IsExpression is Left is Type, which checks Left against a builtin
+// type, an object, or a descriptor.
+type IsExpression struct {
+	Token token.Token
+	Left  Expr
+	Type  *Ident
+}
+
+func (ie *IsExpression) exprNode()            {}
+func (ie *IsExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IsExpression) String() string {
+	return "(" + ie.Left.String() + " is " + ie.Type.String() + ")"
+}
+
+// Null is the null literal.
+type Null struct {
+	Token token.Token
+}
+
+func (n *Null) exprNode()            {}
+func (n *Null) TokenLiteral() string { return n.Token.Literal }
+func (n *Null) String() string       { return n.Token.Literal }
+
+// ErrorExpression is error(Message), which raises a runtime error.
+type ErrorExpression struct {
+	Token   token.Token
+	Message Expr
+}
+
+func (ee *ErrorExpression) exprNode()            {}
+func (ee *ErrorExpression) TokenLiteral() string { return ee.Token.Literal }
+func (ee *ErrorExpression) String() string       { return "error(" + ee.Message.String() + ")" }
+
+// TryExpression is try { Body } catch Name { Catch }. Name is nil when
+// the error is not bound.
+type TryExpression struct {
+	Token token.Token
+	Body  *BlockStatement
+	Name  *Ident
+	Catch *BlockStatement
+}
+
+func (te *TryExpression) exprNode()            {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(te.Body.String())
+	out.WriteString(" catch ")
+	if te.Name != nil {
+		out.WriteString(te.Name.String())
+		out.WriteString(" ")
+	}
+	out.WriteString(te.Catch.String())
+	return out.String()
+}
+
+// DeferStatement is defer Call. The method and its arguments are
+// evaluated straight away, and the call is made when the enclosing
+// method returns.
+type DeferStatement struct {
+	Token token.Token
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) stmtNode()            {}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) String() string       { return "defer " + ds.Call.String() }
+
+// YieldExpression is yield Value. It hands Value to whatever is
+// consuming the generator and evaluates to null once resumed.
+type YieldExpression struct {
+	Token token.Token
+	Value Expr
+}
+
+func (ye *YieldExpression) exprNode()            {}
+func (ye *YieldExpression) TokenLiteral() string { return ye.Token.Literal }
+func (ye *YieldExpression) String() string       { return "yield " + ye.Value.String() }
+
+// SpreadExpression is ...Value, which expands a collection in place
+// inside a call's arguments or an array or map literal.
+type SpreadExpression struct {
+	Token token.Token
+	Value Expr
+}
+
+func (se *SpreadExpression) exprNode()            {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+// RangeExpression is Start..End, or Start..<End when End is excluded,
+// with an optional step.
+type RangeExpression struct {
+	Token     token.Token
+	Start     Expr
+	End       Expr
+	Step      Expr
+	Inclusive bool
+}
+
+func (re *RangeExpression) exprNode()            {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(re.Start.String())
+	out.WriteString(re.Token.Literal)
+	out.WriteString(re.End.String())
+	if re.Step != nil {
+		out.WriteString(" step ")
+		out.WriteString(re.Step.String())
+	}
+	out.WriteString(")")
+	return out.String()
+}
+
+// Property is a property declared in a descriptor or object body,
+// Name = Value, or const Name = Value when Const.
+type Property struct {
+	Token token.Token
+	Name  *Ident
+	Type  *Ident
+	Value Expr
+	Const bool
+}
+
+func (pr *Property) String() string {
+	name := pr.Name.String()
+	if pr.Type != nil {
+		name += " " + pr.Type.String()
+	}
+	if pr.Const {
+		return "const " + name + " = " + pr.Value.String()
+	}
+	return name + " = " + pr.Value.String()
+}
+
+// Members is the body of a descriptor or object. Overloads replace an
+// inherited method, or define an operator when their Name is one, such
+// as + or []. Required holds the methods a descriptor declares without
+// a body, which objects it describes must provide. Validators are named
+// after the property they check, and take its new value.
+type Members struct {
+	Properties []*Property
+	Required   []*FuncLiteral
+	Methods    []*FuncLiteral
+	Overloads  []*FuncLiteral
+	Validators []*FuncLiteral
+}
+
+func (m *Members) String() string {
+	var out []string
+	for _, pr := range m.Properties {
+		out = append(out, pr.String())
+	}
+	for _, fn := range m.Required {
+		out = append(out, fn.String())
+	}
+	for _, fn := range m.Methods {
+		out = append(out, fn.String())
+	}
+	for _, fn := range m.Overloads {
+		out = append(out, fn.String())
+	}
+	for _, fn := range m.Validators {
+		out = append(out, fn.String())
+	}
+	if len(out) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(out, "; ") + " }"
+}
+
+// header prints a describe or object statement's name, followed by its
+// parameters or descriptors when it has any.
+func header(keyword string, name *Ident, list []*Ident, types []*Ident) string {
+	if len(list) == 0 {
+		return keyword + " " + name.String() + " "
+	}
+	return keyword + " " + name.String() + ": " + FormatTypedParameters(list, types, nil) + " "
+}
+
+// DescribeStatement declares a descriptor,
+// describe Name: Parameters { Members }.
+type DescribeStatement struct {
+	Token      token.Token
+	Name       *Ident
+	Parameters []*Ident
+	// Types lines up with Parameters, like FuncLiteral.Types
+	Types []*Ident
+	Members
+}
+
+func (ds *DescribeStatement) stmtNode()            {}
+func (ds *DescribeStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DescribeStatement) String() string {
+	return header("describe", ds.Name, ds.Parameters, ds.Types) + ds.Members.String()
+}
+
+// ObjectStatement declares an object orchestrated from descriptors,
+// object Name: Descriptors { Members }.
+type ObjectStatement struct {
+	Token       token.Token
+	Name        *Ident
+	Descriptors []*Ident
+	Members
+}
+
+func (os *ObjectStatement) stmtNode()            {}
+func (os *ObjectStatement) TokenLiteral() string { return os.Token.Literal }
+func (os *ObjectStatement) String() string {
+	return header("object", os.Name, os.Descriptors, nil) + os.Members.String()
+}
+
+// EnumStatement declares an enumeration, enum Name { Members }.
+type EnumStatement struct {
+	Token   token.Token
+	Name    *Ident
+	Members []*Ident
+}
+
+func (es *EnumStatement) stmtNode()            {}
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStatement) String() string {
+	return "enum " + es.Name.String() + " { " + FormatParameters(es.Members, nil) + " }"
+}
+
+// ForStatement is for Value in Iterable { Body }, or
+// for Key, Value in Iterable { Body }. Key is nil when omitted.
+type ForStatement struct {
+	Token    token.Token
+	Key      *Ident
+	Value    Target
+	Iterable Expr
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) stmtNode()            {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for ")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(fs.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(" ")
+	out.WriteString(fs.Body.String())
+	return out.String()
+}
+
+// ArrayTarget is [a, b, ...Rest], which unpacks an array element by
+// element. Rest is nil when omitted.
+type ArrayTarget struct {
+	Token    token.Token
+	Elements []Target
+	Rest     *Ident
+}
+
+func (at *ArrayTarget) targetNode()          {}
+func (at *ArrayTarget) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayTarget) String() string {
+	var elements []string
+	for _, el := range at.Elements {
+		elements = append(elements, el.String())
+	}
+	if at.Rest != nil {
+		elements = append(elements, "..."+at.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// MapTarget is {name, "key": target, ...Rest}, which unpacks a map by
+// key. Keys are literals and line up with Values. Rest is nil when
+// omitted.
+type MapTarget struct {
+	Token  token.Token
+	Keys   []Expr
+	Values []Target
+	Rest   *Ident
+}
+
+func (mt *MapTarget) targetNode()          {}
+func (mt *MapTarget) TokenLiteral() string { return mt.Token.Literal }
+func (mt *MapTarget) String() string {
+	var entries []string
+	for i, key := range mt.Keys {
+		if ident, ok := mt.Values[i].(*Ident); ok && ident.Value == key.String() {
+			entries = append(entries, ident.String())
+		} else {
+			entries = append(entries, key.String()+":"+mt.Values[i].String())
+		}
+	}
+	if mt.Rest != nil {
+		entries = append(entries, "..."+mt.Rest.String())
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// TargetNames returns every name that target binds, in order.
+func TargetNames(target Target) []*Ident {
+	switch t := target.(type) {
+	case *Ident:
+		return []*Ident{t}
+	case *ArrayTarget:
+		var names []*Ident
+		for _, el := range t.Elements {
+			names = append(names, TargetNames(el)...)
+		}
+		if t.Rest != nil {
+			names = append(names, t.Rest)
+		}
+		return names
+	case *MapTarget:
+		var names []*Ident
+		for _, value := range t.Values {
+			names = append(names, TargetNames(value)...)
+		}
+		if t.Rest != nil {
+			names = append(names, t.Rest)
+		}
+		return names
+	}
+	return nil
+}
+
+// ComprehensionClause is the for Key, Value in Iterable if Condition
+// part of a comprehension. Key and Condition are nil when omitted.
+type ComprehensionClause struct {
+	Token     token.Token
+	Key       *Ident
+	Value     Target
+	Iterable  Expr
+	Condition Expr
+}
+
+func (cc *ComprehensionClause) String() string {
+	var out bytes.Buffer
+	out.WriteString("for ")
+	if cc.Key != nil {
+		out.WriteString(cc.Key.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(cc.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(cc.Iterable.String())
+	if cc.Condition != nil {
+		out.WriteString(" if ")
+		out.WriteString(cc.Condition.String())
+	}
+	return out.String()
+}
+
+// ArrayComprehension is [Element for ... in ...].
+type ArrayComprehension struct {
+	Token   token.Token
+	Element Expr
+	Clause  *ComprehensionClause
+}
+
+func (ac *ArrayComprehension) exprNode()            {}
+func (ac *ArrayComprehension) TokenLiteral() string { return ac.Token.Literal }
+func (ac *ArrayComprehension) String() string {
+	return "[" + ac.Element.String() + " " + ac.Clause.String() + "]"
+}
+
+// MapComprehension is {Key: Value for ... in ...}.
+type MapComprehension struct {
+	Token  token.Token
+	Key    Expr
+	Value  Expr
+	Clause *ComprehensionClause
+}
+
+func (mc *MapComprehension) exprNode()            {}
+func (mc *MapComprehension) TokenLiteral() string { return mc.Token.Literal }
+func (mc *MapComprehension) String() string {
+	return "{" + mc.Key.String() + ":" + mc.Value.String() + " " + mc.Clause.String() + "}"
+}
+
+// SliceExpression is Left[Low:High]. Low and High are nil when omitted.
+// Like an IndexExpression, it can be Optional.
+type SliceExpression struct {
+	Token    token.Token
+	Left     Expr
+	Low      Expr
+	High     Expr
+	Optional bool
+}
+
+func (se *SliceExpression) exprNode()            {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	if se.Optional {
+		out.WriteString("?")
+	}
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
+// HashMap is a map literal. Keys holds the keys of Pairs in source
+// order. A spread entry such as ...other is a key whose value is nil.
 type HashMap struct {
 	Token token.Token
 	Pairs map[Expr]Expr
+	Keys  []Expr
 }
 
 func (h *HashMap) exprNode()            {}
@@ -325,11 +885,136 @@ func (h *HashMap) String() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for k, v := range h.Pairs {
-		pairs = append(pairs, k.String()+":"+v.String())
+	for _, k := range h.Keys {
+		if v := h.Pairs[k]; v != nil {
+			pairs = append(pairs, k.String()+":"+v.String())
+		} else {
+			pairs = append(pairs, k.String())
+		}
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString(("}"))
 	return out.String()
 }
+
+// MatchExpression is match Subject { Arms }. It evaluates to the body
+// of the first arm whose pattern and guard accept the subject.
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expr
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) exprNode()            {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var arms []string
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	return "match " + me.Subject.String() + " { " + strings.Join(arms, ", ") + " }"
+}
+
+// MatchArm is Pattern -> Body, or Pattern if Guard -> Body.
+type MatchArm struct {
+	Token   token.Token
+	Pattern Pattern
+	Guard   Expr
+	Body    Expr
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" -> ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+// WildcardPattern is _, which matches anything.
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// BindingPattern matches anything and binds it to Name.
+type BindingPattern struct {
+	Token token.Token
+	Name  *Ident
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Token.Literal }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+
+// ValuePattern matches a value equal to Value, or contained in it when
+// Value is a range.
+type ValuePattern struct {
+	Token token.Token
+	Value Expr
+}
+
+func (vp *ValuePattern) patternNode()         {}
+func (vp *ValuePattern) TokenLiteral() string { return vp.Token.Literal }
+func (vp *ValuePattern) String() string       { return vp.Value.String() }
+
+// TypePattern is Name TypeName, which matches values of that type and
+// binds them to Name unless Name is _.
+type TypePattern struct {
+	Token    token.Token
+	Name     *Ident
+	TypeName *Ident
+}
+
+func (tp *TypePattern) patternNode()         {}
+func (tp *TypePattern) TokenLiteral() string { return tp.Token.Literal }
+func (tp *TypePattern) String() string       { return tp.Name.String() + " " + tp.TypeName.String() }
+
+// ArrayPattern is [p1, p2, ...rest]. Without Rest it only matches arrays
+// of exactly len(Elements); with Rest, any extra elements are bound to
+// Rest as an array.
+type ArrayPattern struct {
+	Token    token.Token
+	Elements []Pattern
+	Rest     *Ident
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	var elements []string
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPattern is {key: pattern, ...}. It matches maps holding every key
+// whose value matches the key's pattern; other keys are ignored.
+type HashPattern struct {
+	Token  token.Token
+	Keys   []Expr
+	Values []Pattern
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	var pairs []string
+	for i, key := range hp.Keys {
+		pairs = append(pairs, key.String()+": "+hp.Values[i].String())
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/alexjwhite-cb/jet/pkg/object"
 	"strings"
+	"unicode/utf8"
 )
 
 var builtins = map[string]*object.BuiltIn{
@@ -25,7 +26,7 @@ var builtins = map[string]*object.BuiltIn{
 					out = append(out, a.Value)
 
 				default:
-					return newError("argument to `print` not supported, got %s", arg.Type())
+					return newError("argument to `print` not supported, got %s", typeName(arg))
 				}
 			}
 			print(strings.Join(out, ""))
@@ -43,11 +44,76 @@ var builtins = map[string]*object.BuiltIn{
 				return &object.Integer{Value: int64(len(arg.Elements))}
 
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+
+			case *object.Range:
+				return &object.Integer{Value: arg.Len()}
+
+			case *object.Enum:
+				return &object.Integer{Value: int64(len(arg.Members))}
 
 			default:
-				return newError("argument to `len` not supported, got %s", arg.Type())
+				return newError("argument to `len` not supported, got %s", typeName(arg))
+			}
+		},
+	},
+
+	"next": {
+		Method: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments, want 1, got %d", len(args))
+			}
+			it, ok := args[0].(object.Iterator)
+			if !ok {
+				return newError("argument to `next` not supported, got %s", typeName(args[0]))
+			}
+			if value, ok := it.Next(); ok {
+				return value
+			}
+			return NULL
+		},
+	},
+
+	"close": {
+		Method: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments, want 1, got %d", len(args))
+			}
+			it, ok := args[0].(object.Iterator)
+			if !ok {
+				return newError("argument to `close` not supported, got %s", typeName(args[0]))
+			}
+			it.Close()
+			return NULL
+		},
+	},
+
+	"descriptors": {
+		Method: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments, want 1, got %d", len(args))
+			}
+			inst, ok := args[0].(*object.Instance)
+			if !ok {
+				return newError("argument to `descriptors` must be an object, got %s", typeName(args[0]))
+			}
+			descriptors := make([]object.Object, len(inst.Definition.Descriptors))
+			for i, d := range inst.Definition.Descriptors {
+				descriptors[i] = d
 			}
+			return &object.Array{Elements: descriptors}
+		},
+	},
+
+	"str": {
+		Method: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments, want 1, got %d", len(args))
+			}
+			if str, ok := args[0].(*object.String); ok {
+				return str
+			}
+			return &object.String{Value: args[0].Inspect()}
 		},
 	},
 
@@ -58,7 +124,7 @@ var builtins = map[string]*object.BuiltIn{
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+				return newError("argument to `first` must be ARRAY, got %s", typeName(args[0]))
 			}
 
 			arr := args[0].(*object.Array)
@@ -76,7 +142,7 @@ var builtins = map[string]*object.BuiltIn{
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `tail` must be ARRAY, got %s", args[0].Type())
+				return newError("argument to `tail` must be ARRAY, got %s", typeName(args[0]))
 			}
 
 			arr := args[0].(*object.Array)
@@ -97,7 +163,7 @@ var builtins = map[string]*object.BuiltIn{
 			}
 
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `append` must be ARRAY, got %s", args[0].Type())
+				return newError("argument to `append` must be ARRAY, got %s", typeName(args[0]))
 			}
 
 			arr := args[0].(*object.Array)
@@ -109,3 +175,36 @@ var builtins = map[string]*object.BuiltIn{
 		},
 	},
 }
+
+func init() {
+	// array iterates with forEach, which can call back into the
+	// evaluator, so it is added here to avoid an initialisation cycle.
+	builtins["array"] = &object.BuiltIn{Method: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments, want 1, got %d", len(args))
+		}
+		switch arg := args[0].(type) {
+		case *object.Array:
+			elements := make([]object.Object, len(arg.Elements))
+			copy(elements, arg.Elements)
+			return &object.Array{Elements: elements}
+
+		case *object.Range:
+			if arg.Len() > maxArrayLen {
+				return newError("range of %d elements is too large for an array", arg.Len())
+			}
+		}
+		if !isIterable(args[0]) {
+			return newError("argument to `array` not supported, got %s", typeName(args[0]))
+		}
+
+		elements := []object.Object{}
+		if err := forEach(args[0], func(_, value object.Object) object.Object {
+			elements = append(elements, value)
+			return nil
+		}); err != nil {
+			return err
+		}
+		return &object.Array{Elements: elements}
+	}}
+}
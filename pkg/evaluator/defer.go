@@ -0,0 +1,35 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+func evalDeferStatement(node *ast.DeferStatement, env *object.Environment) object.Object {
+	method := eval(node.Call.Function, env)
+	if isError(method) {
+		return method
+	}
+	args, names, err := evalArguments(node.Call, env)
+	if err != nil {
+		return err
+	}
+
+	env.Defer(func() object.Object {
+		return withPosition(applyMethod(method, args, names), node.Call.Token)
+	})
+	return nil
+}
+
+// runDeferred makes the calls deferred in env, most recent first, once
+// the method that env belongs to has produced result. An error from a
+// deferred call replaces result, unless result is already an error.
+func runDeferred(env *object.Environment, result object.Object) object.Object {
+	deferred := env.Deferred()
+	for i := len(deferred) - 1; i >= 0; i-- {
+		if out := deferred[i](); isError(out) && !isError(result) {
+			result = out
+		}
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+// StrictDestructuring makes destructuring an array that is too short,
+// or a map that is missing a key, an error. Otherwise the names that
+// have no value are bound to null.
+var StrictDestructuring = false
+
+// bindTarget unpacks value into the names in target, passing each name
+// and its value to bind. It returns an error if value does not have the
+// shape of target or bind fails, and nil otherwise.
+func bindTarget(target ast.Target, value object.Object, env *object.Environment, bind func(string, object.Object) object.Object) object.Object {
+	switch t := target.(type) {
+	case *ast.Ident:
+		if err := bind(t.Value, value); isError(err) {
+			return withPosition(err, t.Token)
+		}
+		return nil
+
+	case *ast.ArrayTarget:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return withPosition(newError("cannot destructure %s as an array", typeName(value)), t.Token)
+		}
+		if StrictDestructuring && len(array.Elements) < len(t.Elements) {
+			return withPosition(newError("not enough elements to destructure: want %d, got %d",
+				len(t.Elements), len(array.Elements)), t.Token)
+		}
+		for i, el := range t.Elements {
+			var elValue object.Object = NULL
+			if i < len(array.Elements) {
+				elValue = array.Elements[i]
+			}
+			if err := bindTarget(el, elValue, env, bind); err != nil {
+				return err
+			}
+		}
+		if t.Rest != nil {
+			rest := []object.Object{}
+			if len(array.Elements) > len(t.Elements) {
+				rest = append(rest, array.Elements[len(t.Elements):]...)
+			}
+			bind(t.Rest.Value, &object.Array{Elements: rest})
+		}
+		return nil
+
+	case *ast.MapTarget:
+		hash, ok := value.(*object.HashMap)
+		if !ok {
+			return withPosition(newError("cannot destructure %s as a map", typeName(value)), t.Token)
+		}
+		used := make(map[object.HashKey]bool)
+		for i, keyNode := range t.Keys {
+			key := eval(keyNode, env)
+			hashed := key.(object.Hashable).HashKey()
+			used[hashed] = true
+
+			var entry object.Object = NULL
+			if pair, ok := hash.Pairs[hashed]; ok {
+				entry = pair.Value
+			} else if StrictDestructuring {
+				return withPosition(newError("missing key %s to destructure", key.Inspect()), t.Token)
+			}
+			if err := bindTarget(t.Values[i], entry, env, bind); err != nil {
+				return err
+			}
+		}
+		if t.Rest != nil {
+			rest := object.NewHashMap()
+			for _, hashed := range hash.Keys {
+				if !used[hashed] {
+					rest.Set(hashed, hash.Pairs[hashed])
+				}
+			}
+			if err := bind(t.Rest.Value, rest); isError(err) {
+				return withPosition(err, t.Rest.Token)
+			}
+		}
+		return nil
+	}
+	return newError("cannot destructure into %s", target.String())
+}
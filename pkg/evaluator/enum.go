@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+func evalEnumStatement(node *ast.EnumStatement, env *object.Environment) object.Object {
+	members := make([]string, len(node.Members))
+	for i, member := range node.Members {
+		members[i] = member.Value
+	}
+	enum := object.NewEnum(node.Name.Value, members)
+	env.Set(enum.Name, enum)
+	return nil
+}
+
+func isEnumValue(obj object.Object) bool {
+	_, ok := obj.(*object.EnumValue)
+	return ok
+}
+
+// evalEnumInfixExpr compares members of the same enum by the order they
+// were declared in. Members of different enums are never equal, and
+// can't be ordered.
+func evalEnumInfixExpr(op string, left, right object.Object) object.Object {
+	l := left.(*object.EnumValue)
+	r := right.(*object.EnumValue)
+	switch {
+	case op == "==":
+		return nativeBoolToBooleanObj(l == r)
+	case op == "!=":
+		return nativeBoolToBooleanObj(l != r)
+	case l.Enum != r.Enum:
+		return newError("type mismatch: %s %s %s", typeName(left), op, typeName(right))
+	}
+	switch op {
+	case "<":
+		return nativeBoolToBooleanObj(l.Ordinal < r.Ordinal)
+	case ">":
+		return nativeBoolToBooleanObj(l.Ordinal > r.Ordinal)
+	case "<=":
+		return nativeBoolToBooleanObj(l.Ordinal <= r.Ordinal)
+	case ">=":
+		return nativeBoolToBooleanObj(l.Ordinal >= r.Ordinal)
+	default:
+		return newError("unknown operator: %s %s %s", typeName(left), op, typeName(right))
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"github.com/alexjwhite-cb/jet/pkg/ast"
 	"github.com/alexjwhite-cb/jet/pkg/object"
+	"github.com/alexjwhite-cb/jet/pkg/token"
+	"strings"
+	"unicode/utf8"
 )
 
 var (
@@ -12,16 +15,33 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
-func Eval(node ast.Node, env *object.Environment) object.Object {
+// Eval is the entry point to the evaluator. Any Go panic raised while
+// evaluating node is recovered and returned as an *object.Error, so a
+// script can never crash the program embedding it. Generators abandoned
+// since the last evaluation are closed first.
+func Eval(node ast.Node, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError("internal error: %v", r)
+		}
+	}()
+	object.CloseAbandoned()
+	return eval(node, env)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch n := node.(type) {
 	case *ast.Program:
 		return evalProgram(n, env)
 
 	case *ast.ExpressionStmt:
-		return Eval(n.Expression, env)
+		return eval(n.Expression, env)
 
 	case *ast.IntLiteral:
-		return &object.Integer{Value: n.Value}
+		return evalIntLiteral(n)
+
+	case *ast.FloatLiteral:
+		return &object.Float{Value: n.Value}
 
 	case *ast.Boolean:
 		return nativeBoolToBooleanObj(n.Value)
@@ -37,63 +57,124 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Array{Elements: elements}
 
 	case *ast.IndexExpression:
-		left := Eval(n.Left, env)
+		left := eval(n.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(n.Index, env)
+		if n.Optional && left == NULL {
+			return NULL
+		}
+		index := eval(n.Index, env)
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return withPosition(evalIndexExpression(left, index), n.Token)
+
+	case *ast.SliceExpression:
+		return withPosition(evalSliceExpression(n, env), n.Token)
+
+	case *ast.MemberExpression:
+		left := eval(n.Left, env)
+		if isError(left) {
+			return left
+		}
+		if n.Optional && left == NULL {
+			return NULL
+		}
+		return withPosition(evalMemberExpression(left, n.Name.Value), n.Token)
+
+	case *ast.IsExpression:
+		left := eval(n.Left, env)
+		if isError(left) {
+			return left
+		}
+		ok, err := isType(left, n.Type.Value, env)
+		if err != nil {
+			return withPosition(err, n.Type.Token)
+		}
+		return nativeBoolToBooleanObj(ok)
+
+	case *ast.Null:
+		return NULL
 
 	case *ast.HashMap:
-		return evalHashMap(n, env)
+		return withPosition(evalHashMap(n, env), n.Token)
 
 	case *ast.ValueStmt:
-		val := Eval(n.Value, env)
+		val := eval(n.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(n.Name.Value, val)
+		bind := func(name string, value object.Object) object.Object {
+			return assign(env, name, value)
+		}
+		if n.Target != nil {
+			return bindTarget(n.Target, val, env, bind)
+		}
+		if err := bind(n.Name.Value, val); err != nil {
+			return withPosition(err, n.Token)
+		}
+
+	case *ast.PropertyStmt:
+		target := eval(n.Target.Left, env)
+		if isError(target) {
+			return target
+		}
+		val := eval(n.Value, env)
+		if isError(val) {
+			return val
+		}
+		return withPosition(setProperty(target, n.Target.Name.Value, val), n.Token)
 
 	case *ast.Ident:
 		return evalIdentifier(n, env)
 
 	case *ast.FuncLiteral:
-		params := n.Parameters
-		body := n.Body
-		return &object.Method{Parameters: params, Env: env, Body: body}
+		method := newMethod(n, env)
+		if n.Name != nil {
+			env.Set(n.Name.Value, method)
+		}
+		return method
 
 	case *ast.CallExpression:
-		method := Eval(n.Function, env)
+		method := eval(n.Function, env)
 		if isError(method) {
 			return method
 		}
-		args := evalExpressions(n.Args, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		args, names, err := evalArguments(n, env)
+		if err != nil {
+			return err
 		}
 
-		return applyMethod(method, args)
+		result := withPosition(applyMethod(method, args, names), n.Token)
+		if err, ok := result.(*object.Error); ok {
+			err.Stack = append(err.Stack, fmt.Sprintf("%s at line %d, col %d", n.Function.String(), n.Token.Line, n.Token.Col))
+		}
+		return result
 
 	case *ast.PrefixExpression:
-		right := Eval(n.Right, env)
+		right := eval(n.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpressions(n.Operator, right)
+		return withPosition(evalPrefixExpressions(n.Operator, right), n.Token)
 
 	case *ast.InfixExpression:
-		left := Eval(n.Left, env)
+		left := eval(n.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(n.Right, env)
+		if n.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+			return eval(n.Right, env)
+		}
+		right := eval(n.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(n.Operator, left, right)
+		return withPosition(evalInfixExpression(n.Operator, left, right), n.Token)
 
 	case *ast.BlockStatement:
 		return evalBlockStatement(n, env)
@@ -101,8 +182,47 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(n, env)
 
+	case *ast.ArrayComprehension:
+		return evalArrayComprehension(n, env)
+
+	case *ast.MapComprehension:
+		return evalMapComprehension(n, env)
+
+	case *ast.YieldExpression:
+		return evalYieldExpression(n, env)
+
+	case *ast.SpreadExpression:
+		return withPosition(newError("... can only be used in call arguments and array or map literals"), n.Token)
+
+	case *ast.RangeExpression:
+		return withPosition(evalRangeExpression(n, env), n.Token)
+
+	case *ast.ForStatement:
+		return evalForStatement(n, env)
+
+	case *ast.TryExpression:
+		return evalTryExpression(n, env)
+
+	case *ast.ErrorExpression:
+		return evalErrorExpression(n, env)
+
+	case *ast.DescribeStatement:
+		return evalDescribeStatement(n, env)
+
+	case *ast.ObjectStatement:
+		return evalObjectStatement(n, env)
+
+	case *ast.EnumStatement:
+		return evalEnumStatement(n, env)
+
+	case *ast.DeferStatement:
+		return evalDeferStatement(n, env)
+
+	case *ast.MatchExpression:
+		return withPosition(evalMatchExpression(n, env), n.Token)
+
 	case *ast.ReturnStatement:
-		val := Eval(n.Value, env)
+		val := eval(n.Value, env)
 		if isError(val) {
 			return val
 		}
@@ -115,6 +235,16 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// withPosition records the position of tok on obj if it is an error
+// that has not already been positioned closer to its cause.
+func withPosition(obj object.Object, tok token.Token) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+		err.Line = tok.Line
+		err.Col = tok.Col
+	}
+	return obj
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -126,7 +256,7 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, stmt := range program.Statements {
-		result = Eval(stmt, env)
+		result = eval(stmt, env)
 
 		switch retVal := result.(type) {
 		case *object.ReturnValue:
@@ -140,13 +270,13 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 }
 
 func evalIdentifier(node *ast.Ident, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
+	if val, ok := env.GetAt(node.Depth, node.Slot, node.Value); ok {
 		return val
 	}
 	if builtin, ok := builtins[node.Value]; ok {
 		return builtin
 	}
-	return newError("identifier not found: %s", node.Value)
+	return withPosition(newError("identifier not found: %s", node.Value), node.Token)
 }
 
 func nativeBoolToBooleanObj(in bool) *object.Boolean {
@@ -160,7 +290,15 @@ func evalExpressions(exps []ast.Expr, env *object.Environment) []object.Object {
 	var results []object.Object
 
 	for _, e := range exps {
-		evaluated := Eval(e, env)
+		if spread, ok := e.(*ast.SpreadExpression); ok {
+			elements, err := evalSpread(spread, env)
+			if err != nil {
+				return []object.Object{err}
+			}
+			results = append(results, elements...)
+			continue
+		}
+		evaluated := eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -170,14 +308,75 @@ func evalExpressions(exps []ast.Expr, env *object.Environment) []object.Object {
 	return results
 }
 
+// evalArguments evaluates a call's arguments, expanding any spreads, and
+// returns them with the name of each named argument, or "" for each
+// positional one.
+func evalArguments(call *ast.CallExpression, env *object.Environment) ([]object.Object, []string, object.Object) {
+	var args []object.Object
+	var names []string
+	for i, e := range call.Args {
+		if spread, ok := e.(*ast.SpreadExpression); ok {
+			elements, err := evalSpread(spread, env)
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, elements...)
+			names = append(names, make([]string, len(elements))...)
+			continue
+		}
+
+		evaluated := eval(e, env)
+		if isError(evaluated) {
+			return nil, nil, evaluated
+		}
+		args = append(args, evaluated)
+		if i < len(call.Names) && call.Names[i] != nil {
+			names = append(names, call.Names[i].Value)
+		} else {
+			names = append(names, "")
+		}
+	}
+	return args, names, nil
+}
+
+// evalSpread evaluates the collection in a spread and returns its
+// elements: those of an array or range, or the characters of a string.
+func evalSpread(spread *ast.SpreadExpression, env *object.Environment) ([]object.Object, object.Object) {
+	value := eval(spread.Value, env)
+	if isError(value) {
+		return nil, value
+	}
+
+	switch value := value.(type) {
+	case *object.Array:
+		return value.Elements, nil
+	case *object.String:
+	case *object.Range:
+		if value.Len() > maxArrayLen {
+			return nil, withPosition(newError("range of %d elements is too large to spread", value.Len()), spread.Token)
+		}
+	default:
+		return nil, withPosition(newError("cannot spread %s here", typeName(value)), spread.Token)
+	}
+
+	var elements []object.Object
+	forEach(value, func(_, element object.Object) object.Object {
+		elements = append(elements, element)
+		return nil
+	})
+	return elements, nil
+}
+
 func evalPrefixExpressions(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
 		return evalNotOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOpExpression(right)
+	case "~":
+		return evalBitNotPrefixOpExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError("unknown operator: %s%s", operator, typeName(right))
 	}
 }
 
@@ -194,18 +393,32 @@ func evalNotOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOpExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
-	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
-}
-
 func evalInfixExpression(op string, left, right object.Object) object.Object {
+	if result, ok := evalOperatorOverload(op, left, right); ok {
+		return result
+	}
 	switch {
-	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), op, right.Type())
+	case op == "in":
+		return evalInExpression(left, right)
+	case op == "*" && left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ:
+		return evalStringRepetition(left, right)
+	case op == "*" && left.Type() == object.INTEGER_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringRepetition(right, left)
+	case op == "+" && left.Type() == object.STRING_OBJ && right.Type() != object.STRING_OBJ:
+		return newError("type mismatch: %s + %s; convert with str(%s) to concatenate",
+			typeName(left), typeName(right), right.Inspect())
+	case op == "+" && left.Type() != object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return newError("type mismatch: %s + %s; convert with str(%s) to concatenate",
+			typeName(left), typeName(right), left.Inspect())
+	case (op == "==" || op == "!=") && (left == NULL || right == NULL):
+		return nativeBoolToBooleanObj((left == right) == (op == "=="))
+	case isNumber(left) && isNumber(right) &&
+		(left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ):
+		return evalFloatInfixExpr(op, left, right)
+	case isEnumValue(left) && isEnumValue(right):
+		return evalEnumInfixExpr(op, left, right)
+	case left.Type() != right.Type() || typeName(left) != typeName(right):
+		return newError("type mismatch: %s %s %s", typeName(left), op, typeName(right))
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpr(op, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
@@ -215,22 +428,18 @@ func evalInfixExpression(op string, left, right object.Object) object.Object {
 	case op == "!=":
 		return nativeBoolToBooleanObj(left != right)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+		return newError("unknown operator: %s %s %s", typeName(left), op, typeName(right))
 	}
 }
 
-func evalIntegerInfixExpr(op string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+// evalStringInfixExpr compares strings lexicographically by code point,
+// which is the same as Go's byte-wise ordering for valid UTF-8.
+func evalStringInfixExpr(op string, left, right object.Object) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
 	switch op {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
-	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
-	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
-	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.String{Value: leftVal + rightVal}
 	case "<=":
 		return nativeBoolToBooleanObj(leftVal <= rightVal)
 	case ">=":
@@ -244,39 +453,75 @@ func evalIntegerInfixExpr(op string, left, right object.Object) object.Object {
 	case "!=":
 		return nativeBoolToBooleanObj(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+		return newError("unknown operator: %s %s %s", typeName(left), op, typeName(right))
 	}
 }
 
-func evalStringInfixExpr(op string, left, right object.Object) object.Object {
-	if op != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+// maxStringLen caps the length in bytes of a string built by
+// repetition, so a mistyped count fails cleanly instead of exhausting
+// memory.
+const maxStringLen = 1 << 26
+
+func evalStringRepetition(str, count object.Object) object.Object {
+	c, ok := count.(*object.Integer)
+	if !ok {
+		return newError("repeat count too large: %s", count.Inspect())
 	}
+	n := c.Value
+	if n < 0 {
+		return newError("negative repeat count: %d", n)
+	}
+	value := str.(*object.String).Value
+	if len(value) > 0 && n > int64(maxStringLen/len(value)) {
+		return newError("repeat count too large: %d", n)
+	}
+	return &object.String{Value: strings.Repeat(value, int(n))}
+}
 
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
+func evalInExpression(left, right object.Object) object.Object {
+	switch container := right.(type) {
+	case *object.String:
+		substr, ok := left.(*object.String)
+		if !ok {
+			return newError("type mismatch: %s in %s", typeName(left), typeName(right))
+		}
+		return nativeBoolToBooleanObj(strings.Contains(container.Value, substr.Value))
+	case *object.Range:
+		switch n := left.(type) {
+		case *object.Integer:
+			return nativeBoolToBooleanObj(container.Contains(n.Value))
+		case *object.BigInteger:
+			return FALSE
+		default:
+			return newError("type mismatch: %s in %s", typeName(left), typeName(right))
+		}
+	default:
+		return newError("unknown operator: %s in %s", typeName(left), typeName(right))
+	}
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+	condition := eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
+	var result object.Object
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		result = eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
-	} else {
+		result = eval(ie.Alternative, env)
+	}
+	if result == nil {
 		return NULL
 	}
+	return result
 }
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, stmt := range block.Statements {
-		result = Eval(stmt, env)
+		result = eval(stmt, env)
 
 		if result != nil {
 			switch result.Type() {
@@ -290,58 +535,225 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
+	if inst, ok := left.(*object.Instance); ok && inst.Operators["[]"] != nil {
+		return applyMethod(inst.Operators["[]"], []object.Object{index}, nil)
+	}
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalRangeIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError("index operator not supported: %s", typeName(left))
 	}
 }
 
+// evalMemberExpression reads left.name. On a map this is the value of
+// the key "name", or null if there is no such key.
+func evalMemberExpression(left object.Object, name string) object.Object {
+	switch left := left.(type) {
+	case *object.HashMap:
+		key := &object.String{Value: name}
+		if pair, ok := left.Pairs[key.HashKey()]; ok {
+			return pair.Value
+		}
+		return NULL
+	case *object.Instance:
+		if value, ok := left.Env.GetLocal(name); ok {
+			return value
+		}
+		return newError("%s has no property %s", left.Definition.Name, name)
+	case *object.DescriptorView:
+		if left.Descriptor.Declares(name) {
+			value, _ := left.Instance.Env.GetLocal(name)
+			return value
+		}
+		return newError("%s does not describe %s", left.Descriptor.Name, name)
+	case *object.Enum:
+		if member, ok := left.Member(name); ok {
+			return member
+		}
+		return newError("%s has no member %s", left.Name, name)
+	case *object.EnumValue:
+		switch name {
+		case "Name":
+			return &object.String{Value: left.Name}
+		case "Ordinal":
+			return &object.Integer{Value: left.Ordinal}
+		}
+		return newError("cannot read %s of %s", name, left.Inspect())
+	case *object.Descriptor:
+		if value, ok := left.Constants[name]; ok {
+			return value
+		}
+		return newError("%s has no constant %s", left.Name, name)
+	}
+	return newError("cannot read %s of %s", name, typeName(left))
+}
+
 func evalHashMap(node *ast.HashMap, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHashMap()
+
+	for _, keyNode := range node.Keys {
+		valueNode := node.Pairs[keyNode]
+		if spread, ok := keyNode.(*ast.SpreadExpression); ok {
+			other := eval(spread.Value, env)
+			if isError(other) {
+				return other
+			}
+			otherHash, ok := other.(*object.HashMap)
+			if !ok {
+				return withPosition(newError("cannot spread %s into a map", typeName(other)), spread.Token)
+			}
+			for _, hashed := range otherHash.Keys {
+				hash.Set(hashed, otherHash.Pairs[hashed])
+			}
+			continue
+		}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+		key := eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newError("unusable as hash key: %s", typeName(key))
 		}
 
-		value := Eval(valueNode, env)
+		value := eval(valueNode, env)
 		if isError(value) {
-			fmt.Printf("here")
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.HashMap{Pairs: pairs}
+	return hash
 }
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObj := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObj.Elements) - 1)
-	if idx < 0 || idx > max {
+	idx, ok := elementIndex(index, len(arrayObj.Elements))
+	if !ok {
 		return NULL
 	}
 	return arrayObj.Elements[idx]
 }
 
+// evalStringIndexExpression returns the character at index, counting
+// characters rather than bytes.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx, ok := elementIndex(index, len(runes))
+	if !ok {
+		return NULL
+	}
+	return &object.String{Value: string(runes[idx])}
+}
+
+// elementIndex resolves an index into a sequence of length n, where a
+// negative index counts back from the end. ok is false when the index
+// is out of range.
+func elementIndex(index object.Object, n int) (int, bool) {
+	integer, ok := index.(*object.Integer)
+	if !ok {
+		return 0, false
+	}
+	idx := integer.Value
+	if idx < 0 {
+		idx += int64(n)
+	}
+	if idx < 0 || idx >= int64(n) {
+		return 0, false
+	}
+	return int(idx), true
+}
+
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	if node.Optional && left == NULL {
+		return NULL
+	}
+
+	var length int
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		length = utf8.RuneCountInString(left.Value)
+	default:
+		return newError("slice operator not supported: %s", typeName(left))
+	}
+
+	low, err := sliceBound(node.Low, env, 0, length)
+	if err != nil {
+		return err
+	}
+	high, err := sliceBound(node.High, env, length, length)
+	if err != nil {
+		return err
+	}
+	if high < low {
+		high = low
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		elements := make([]object.Object, high-low)
+		copy(elements, left.Elements[low:high])
+		return &object.Array{Elements: elements}
+	default:
+		runes := []rune(left.(*object.String).Value)
+		return &object.String{Value: string(runes[low:high])}
+	}
+}
+
+// sliceBound evaluates one bound of a slice over a sequence of length
+// n. A missing bound takes def, a negative bound counts back from the
+// end, and the result is clamped to the sequence.
+func sliceBound(node ast.Expr, env *object.Environment, def, n int) (int, object.Object) {
+	if node == nil {
+		return def, nil
+	}
+	bound := eval(node, env)
+	if isError(bound) {
+		return 0, bound
+	}
+
+	var idx int64
+	switch bound := bound.(type) {
+	case *object.Integer:
+		idx = bound.Value
+	case *object.BigInteger:
+		idx = int64(n) * int64(bound.Value.Sign())
+	default:
+		return 0, newError("slice bound must be INTEGER, got %s", typeName(bound))
+	}
+	if idx < 0 {
+		idx += int64(n)
+	}
+	if idx < 0 {
+		return 0, nil
+	}
+	if idx > int64(n) {
+		return n, nil
+	}
+	return int(idx), nil
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.HashMap)
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError("unusable as hash key: %s", typeName(index))
 	}
 	pair, ok := hashObject.Pairs[key.HashKey()]
 	if !ok {
@@ -350,30 +762,168 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 }
 
-func applyMethod(fn object.Object, args []object.Object) object.Object {
+// newMethod makes the method that lit declares, closing over env.
+func newMethod(lit *ast.FuncLiteral, env *object.Environment) *object.Method {
+	method := &object.Method{
+		Parameters: lit.Parameters,
+		Defaults:   lit.Defaults,
+		Patterns:   lit.Patterns,
+		Types:      lit.Types,
+		ReturnType: lit.ReturnType,
+		Body:       lit.Body,
+		Env:        env,
+		Generator:  lit.IsGenerator,
+	}
+	if lit.Name != nil {
+		method.Name = lit.Name.Value
+	}
+	return method
+}
+
+// maxCallDepth caps how deeply method calls can nest, so that runaway
+// recursion fails with an error instead of overflowing the Go stack,
+// which can't be recovered from.
+const maxCallDepth = 10000
+
+// callDepth counts the method calls in progress. Generator bodies run on
+// their own goroutines, but never at the same time as their caller.
+var callDepth int
+
+// applyMethod calls fn with args. names lines up with args and holds
+// the parameter name of each named argument, or "" for a positional one;
+// it may be nil when every argument is positional.
+func applyMethod(fn object.Object, args []object.Object, names []string) object.Object {
+	if callDepth >= maxCallDepth {
+		return newError("maximum call depth exceeded")
+	}
+	callDepth++
+	defer func() { callDepth-- }()
+
 	switch method := fn.(type) {
 	case *object.Method:
-		extendedEnv := extendFunctionEnv(method, args)
-		evaluated := Eval(method.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		extendedEnv, err := extendFunctionEnv(method, args, names)
+		if err != nil {
+			return err
+		}
+		if method.Generator {
+			return checkReturnType(method, newGenerator(method, extendedEnv))
+		}
+		evaluated := eval(method.Body, extendedEnv)
+		return checkReturnType(method, runDeferred(extendedEnv, unwrapReturnValue(evaluated)))
+	case *object.ObjectDefinition:
+		return newInstance(method, args, names)
 	case *object.BuiltIn:
-		return method.Method(args...)
+		for _, name := range names {
+			if name != "" {
+				return newError("built-in methods do not take named arguments, got %s", name)
+			}
+		}
+		if result := method.Method(args...); result != nil {
+			return result
+		}
+		return NULL
 	}
-	return newError("not a function: %s", fn.Type())
+	return newError("not a function: %s", typeName(fn))
 }
 
-func extendFunctionEnv(fn *object.Method, args []object.Object) *object.Environment {
+// extendFunctionEnv binds args to fn's parameters in a new environment.
+// Positional arguments fill parameters in order, named arguments fill
+// the parameter they name, and any parameter left over takes its
+// default. Defaults are evaluated in the new environment, so they may
+// refer to earlier parameters.
+func extendFunctionEnv(fn *object.Method, args []object.Object, names []string) (*object.Environment, object.Object) {
+	bound := make([]object.Object, len(fn.Parameters))
+	named := false
+	for i, arg := range args {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		if name == "" {
+			if i >= len(fn.Parameters) {
+				return nil, wrongArgumentCount(fn, len(args))
+			}
+			bound[i] = arg
+			continue
+		}
+
+		named = true
+		idx := parameterIndex(fn, name)
+		if idx < 0 {
+			return nil, newError("unknown parameter: %s", name)
+		}
+		if bound[idx] != nil {
+			return nil, newError("argument %s given more than once", name)
+		}
+		bound[idx] = arg
+	}
+
 	env := object.NewEnclosedEnvironment(fn.Env)
-	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+	for i, param := range fn.Parameters {
+		value := bound[i]
+		if value == nil {
+			if i >= len(fn.Defaults) || fn.Defaults[i] == nil {
+				if named {
+					return nil, newError("missing argument for parameter %s", param.Value)
+				}
+				return nil, wrongArgumentCount(fn, len(args))
+			}
+			value = eval(fn.Defaults[i], env)
+			if isError(value) {
+				return nil, value
+			}
+		}
+		if i < len(fn.Types) && fn.Types[i] != nil {
+			if err := checkType(value, fn.Types[i].Value, fn.Env, "argument %d to %s", i+1, methodName(fn)); err != nil {
+				return nil, err
+			}
+		}
+		if i < len(fn.Patterns) && fn.Patterns[i] != nil {
+			if err := bindTarget(fn.Patterns[i], value, env, env.Set); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		env.Set(param.Value, value)
 	}
-	return env
+	return env, nil
+}
+
+func parameterIndex(fn *object.Method, name string) int {
+	for i, param := range fn.Parameters {
+		if param.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func wrongArgumentCount(fn *object.Method, got int) *object.Error {
+	required := requiredParameters(fn)
+	if required == len(fn.Parameters) {
+		return newError("wrong number of arguments, want %d, got %d", required, got)
+	}
+	return newError("wrong number of arguments, want %d to %d, got %d", required, len(fn.Parameters), got)
+}
+
+// requiredParameters counts fn's parameters that have no default.
+func requiredParameters(fn *object.Method) int {
+	required := 0
+	for i := range fn.Parameters {
+		if i >= len(fn.Defaults) || fn.Defaults[i] == nil {
+			required++
+		}
+	}
+	return required
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
 	}
+	if obj == nil {
+		return NULL
+	}
 	return obj
 }
 
@@ -1,16 +1,22 @@
 package evaluator
 
 import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
 	"github.com/alexjwhite-cb/jet/pkg/lexer"
 	"github.com/alexjwhite-cb/jet/pkg/object"
 	"github.com/alexjwhite-cb/jet/pkg/parser"
+	"github.com/alexjwhite-cb/jet/pkg/resolver"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
+	resolver.Resolve(program)
 	env := object.NewEnvironment()
 	return Eval(program, env)
 }
@@ -35,6 +41,22 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3*3*3+10", 37},
 		{"3*(3*3)+10", 37},
 		{"(5+10*2+15/3)*2+-10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 7 % 3 * 2", 4},
+		{"2 ** 10", 1024},
+		{"2 ** 0", 1},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"256 >> 4", 16},
+		{"-16 >> 2", -4},
+		{"1 | 2 & 3", 3},
 	}
 
 	for _, tt := range tests {
@@ -56,6 +78,59 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"7.5 % 2", 1.5},
+		{"2.0 ** 0.5 ** 2", 1.189207115002721},
+		{"1e3 - 1", 999},
+		{"0x10 * 0.5", 8},
+		{"99999999999999999999 * 1.0", 1e20},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+
+	boolTests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 < 2", true},
+		{"2 >= 2.0", true},
+		{"0.1 + 0.2 == 0.3", false},
+		{"1.0 == 1", true},
+		{"1.5 != 1.5", false},
+	}
+
+	for _, tt := range boolTests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float, got %T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value, got %g, want %g", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -196,7 +271,27 @@ func TestErrorHandling(t *testing.T) {
 }`, "unknown operator: BOOLEAN + BOOLEAN"},
 		{"foobar", "identifier not found: foobar"},
 		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{`"Hello" + 5`, "type mismatch: STRING + INTEGER; convert with str(5) to concatenate"},
+		{`true + "Hello"`, "type mismatch: BOOLEAN + STRING; convert with str(true) to concatenate"},
+		{`"ab" * -1`, "negative repeat count: -1"},
+		{`"ab" * 9223372036854775807`, "repeat count too large: 9223372036854775807"},
+		{`"ab" * 100000000`, "repeat count too large: 100000000"},
+		{"1 << -1", "negative shift amount: -1"},
+		{"1 >> -2", "negative shift amount: -2"},
+		{"2 ** -1", "negative exponent: -1"},
+		{"2 ** 10000000000", "result too large: 2-bit integer ** 10000000000"},
+		{"1 << 4294967295", "result too large: 1-bit integer << 4294967295"},
+		{"x = 2 ** 67108860\nx * x", "result too large: multiplying 67108861-bit and 67108861-bit integers"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+		{`"a" % "b"`, "unknown operator: STRING % STRING"},
+		{`1 in "abc"`, "type mismatch: INTEGER in STRING"},
+		{`"a" in 5`, "unknown operator: STRING in INTEGER"},
 		{`{"name": "Jet"}[meth: x { x }];`, "unusable as hash key: METHOD"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"1.5 / 0", "division by zero"},
+		{`{"a": 1}[0:1]`, "slice operator not supported: HASH"},
+		{`[1, 2][true:]`, "slice bound must be INTEGER, got BOOLEAN"},
+		{`1.5 + "a"`, "type mismatch: FLOAT + STRING; convert with str(1.5) to concatenate"},
 	}
 
 	for _, tt := range tests {
@@ -213,6 +308,183 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestIntegerOverflowPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"2 ** 64", "18446744073709551616"},
+		{"1 << 70", "1180591620717411303424"},
+		{"99999999999999999999", "99999999999999999999"},
+		{"99999999999999999999 * 10 + 9", "999999999999999999999"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"~99999999999999999999", "-100000000000000000000"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		result, ok := evaluated.(*object.BigInteger)
+		if !ok {
+			t.Errorf("%q: object is not BigInteger, got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestBigIntegerArithmetic(t *testing.T) {
+	intTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"99999999999999999999 - 99999999999999999998", 1},
+		{"(9223372036854775807 + 1) - 1", 9223372036854775807},
+		{"-9223372036854775808", -9223372036854775808},
+		{"99999999999999999999 % 10", 9},
+		{"(2 ** 64) >> 60", 16},
+		{"(2 ** 64) / (2 ** 62)", 4},
+		{"-(-9223372036854775807 - 1) - 1", 9223372036854775807},
+	}
+	for _, tt := range intTests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	boolTests := []struct {
+		input    string
+		expected bool
+	}{
+		{"2 ** 64 > 1", true},
+		{"1 < 2 ** 64", true},
+		{"2 ** 64 == 2 ** 64", true},
+		{"2 ** 64 == 2 ** 65", false},
+		{"2 ** 64 != 2 ** 63 * 2", false},
+		{"2 ** 1000000 > 2 ** 999999", true},
+		{"(-(2 ** 64)) ** 3 < 0", true},
+		{"(2 ** 64) << 1000000 > 0", true},
+		{"-(2 ** 64) < 0", true},
+	}
+	for _, tt := range boolTests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+
+	testIntegerObject(t, testEval("{2 ** 64: 1, 1: 2}[2 ** 63 * 2]"), 1)
+	testIntegerObject(t, testEval("{2 ** 64: 1, 1: 2}[2 ** 64 / 2 ** 64]"), 2)
+}
+
+func TestIntegerOverflowError(t *testing.T) {
+	IntegerOverflow = ErrorOnOverflow
+	defer func() { IntegerOverflow = PromoteOnOverflow }()
+
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"2 ** 64", "integer overflow: 2 ** 64"},
+		{"1 << 63", "integer overflow: 1 << 63"},
+		{"-(-9223372036854775807 - 1)", "integer overflow: -(-9223372036854775808)"},
+		{"99999999999999999999", "integer overflow: 99999999999999999999 does not fit in 64 bits"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q, got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("Expected error: %s\nGot: %s", tt.expectedMessage, errObj.Message)
+		}
+	}
+
+	testIntegerObject(t, testEval("9223372036854775806 + 1"), 9223372036854775807)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedLine    int
+		expectedCol     int
+	}{
+		{"10 / 0", "division by zero", 1, 4},
+		{"10 % 0", "modulo by zero", 1, 4},
+		{"x = 0\n5 / x", "division by zero", 2, 3},
+		{"add = meth: x, y { x + y }; add(1)", "wrong number of arguments, want 2, got 1", 1, 32},
+		{"add = meth: x, y { x + y }; add(1, 2, 3)", "wrong number of arguments, want 2, got 3", 1, 32},
+		{"5(1)", "not a function: INTEGER", 1, 2},
+		{`f = meth: x { x / 0 }
+f(1)`, "division by zero", 1, 17},
+		{"len(1)", "argument to `len` not supported, got INTEGER", 1, 4},
+		{"1 + foo", "identifier not found: foo", 1, 5},
+		{`x = "ab" * 100000000`, "repeat count too large: 100000000", 1, 10},
+		{"meth f { (f())-> }\nf()", "maximum call depth exceeded", 1, 12},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q, got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("Expected error: %s\nGot: %s", tt.expectedMessage, errObj.Message)
+		}
+		if errObj.Line != tt.expectedLine || errObj.Col != tt.expectedCol {
+			t.Errorf("wrong position for %q, expected %d:%d, got %d:%d",
+				tt.input, tt.expectedLine, tt.expectedCol, errObj.Line, errObj.Col)
+		}
+	}
+}
+
+func TestEvalRecoversFromPanics(t *testing.T) {
+	node := &ast.PrefixExpression{Operator: "-"}
+	evaluated := Eval(node, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got %T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(errObj.Message, "internal error: ") {
+		t.Errorf("unexpected error message, got %q", errObj.Message)
+	}
+}
+
+func TestTryCatchesPanics(t *testing.T) {
+	input := `try { x } catch err { err["message"] }`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	try := program.Statements[0].(*ast.ExpressionStmt).Expression.(*ast.TryExpression)
+	try.Body.Statements[0] = &ast.ExpressionStmt{Expression: &ast.PrefixExpression{Operator: "-"}}
+
+	evaluated := Eval(program, object.NewEnvironment())
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("catch block not run, got %T (%+v)", evaluated, evaluated)
+	}
+	if !strings.HasPrefix(str.Value, "internal error: ") {
+		t.Errorf("unexpected error message, got %q", str.Value)
+	}
+}
+
+func TestEmptyResultsAreNull(t *testing.T) {
+	tests := []string{
+		"f = meth {}; f()",
+		"x = if true {}; x",
+		`puts("hello")`,
+	}
+
+	for _, input := range tests {
+		testNullObject(t, testEval(input))
+	}
+}
+
 func TestValueStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -269,6 +541,94 @@ func TestMethodApplication(t *testing.T) {
 	}
 }
 
+func TestDefaultsAndNamedArguments(t *testing.T) {
+	connect := "meth connect: host, port = 8080 { (host + \":\" + str(port))-> }\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{connect + `connect("x")`, "x:8080"},
+		{connect + `connect("x", 1)`, "x:1"},
+		{connect + `connect(host: "x", port: 1)`, "x:1"},
+		{connect + `connect(port: 1, host: "x")`, "x:1"},
+		{connect + `connect("x", port: 2)`, "x:2"},
+		{connect + `connect(port: 2)`, "missing argument for parameter host"},
+		{connect + `connect()`, "wrong number of arguments, want 1 to 2, got 0"},
+		{connect + `connect("x", 1, 2)`, "wrong number of arguments, want 1 to 2, got 3"},
+		{connect + `connect("x", hots: "y")`, "unknown parameter: hots"},
+		{connect + `connect("x", host: "y")`, "argument host given more than once"},
+		{"f = meth: a, b = a + a { (b)-> }; str(f(2))", "4"},
+		{"n = 1; f = meth: a = n { (a)-> }; n = 5; str(f())", "5"},
+		{"f = meth: a = 1 / 0 { a }; f()", "division by zero"},
+		{"f = meth: a = 1 / 0 { a }; str(f(2))", "2"},
+		{`len(x: "abc")`, "built-in methods do not take named arguments, got x"},
+		{"meth double: x { (x * 2)-> }; str(double(4))", "8"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch result := evaluated.(type) {
+		case *object.Error:
+			if result.Message != tt.expected {
+				t.Errorf("%s: expected %q, got error %q", tt.input, tt.expected, result.Message)
+			}
+		case *object.String:
+			if result.Value != tt.expected {
+				t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, result.Value)
+			}
+		default:
+			t.Errorf("%s: expected %q, got %T (%+v)", tt.input, tt.expected, evaluated, evaluated)
+		}
+	}
+
+	method := testEval("meth: host, port = 8080 { host }")
+	expected := "meth: host, port = 8080 { host }"
+	if method.Inspect() != expected {
+		t.Errorf("expected Inspect() %q, got %q", expected, method.Inspect())
+	}
+}
+
+func TestSpreadExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add = meth: a, b, c { (a + b + c)-> }; args = [1, 2, 3]; add(...args)", "6"},
+		{"add = meth: a, b, c { (a + b + c)-> }; add(1, ...[2, 3])", "6"},
+		{"add = meth: a, b, c = 10 { (a + b + c)-> }; add(...[1, 2])", "13"},
+		{"add = meth: a, b, c = 10 { (a + b + c)-> }; add(...[1], b: 2)", "13"},
+		{"add = meth: a, b { (a + b)-> }; add(...[1, 2, 3])", "wrong number of arguments, want 2, got 3"},
+		{"add = meth: a, b { (a + b)-> }; add(...[])", "wrong number of arguments, want 2, got 0"},
+		{`len(...["abc"])`, "3"},
+		{`len(...["a", "b"])`, "len: incorrect argument count; want 1, got 2"},
+		{"a = [1, 2]; b = [3]; [...a, ...b, 4]", "[1, 2, 3, 4]"},
+		{"[0, ...1..3]", "[0, 1, 2, 3]"},
+		{`[..."hé"]`, "[h, é]"},
+		{"[...[]]", "[]"},
+		{`d = {"host": "a", "port": 1}; m = {...d, "port": 2}; m["host"] + str(m["port"])`, "a2"},
+		{`d = {"port": 1}; m = {"port": 2, ...d}; str(m["port"])`, "1"},
+		{`m = {...{"a": 1}, ...{"b": 2}}; str(m["a"] + m["b"])`, "3"},
+		{"[...5]", "cannot spread INTEGER here"},
+		{`{...[1]}`, "cannot spread ARRAY into a map"},
+		{`f = meth: x { x }; f(...{"x": 1})`, "cannot spread HASH here"},
+		{"x = ...[1]", "... can only be used in call arguments and array or map literals"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		var got string
+		switch result := evaluated.(type) {
+		case *object.Error:
+			got = result.Message
+		default:
+			got = evaluated.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `newAdder = meth: x {
 				(meth: y { x + y })->
@@ -278,6 +638,1065 @@ func TestClosures(t *testing.T) {
 	testIntegerObject(t, testEval(input), 5)
 }
 
+func TestRangeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"len(0..10)", 11},
+		{`len("héllo, 世界")`, 9},
+		{"len(0..<10)", 10},
+		{"len(0..10 step 3)", 4},
+		{"len(10..0)", 0},
+		{"len(0..9223372036854775806)", 9223372036854775807},
+		{"(0..<10)[3]", 3},
+		{"(0..10 step 5)[-1]", 10},
+		{"(10..0 step -2)[1]", 8},
+		{"(0..<10)[10]", nil},
+		{"5 in 0..<10", true},
+		{"10 in 0..<10", false},
+		{"4 in 0..10 step 2", true},
+		{"n = 3; n in 1..n", true},
+		{"array(1..3)", "[1, 2, 3]"},
+		{"array(3..1 step -1)", "[3, 2, 1]"},
+		{"array(0..<0)", "[]"},
+		{`array("héllo")`, `[h, é, l, l, o]`},
+		{"0..<10 step 2", "0..<10 step 2"},
+		{"0..1.5", "range bounds must be 64-bit INTEGER, got FLOAT"},
+		{"0..2 ** 64", "range bound 18446744073709551616 does not fit in 64 bits"},
+		{"0..10 step 0", "range step cannot be zero"},
+		{"array(0..1000000000000)", "range of 1000000000001 elements is too large for an array"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, errObj.Message)
+				}
+			} else if evaluated.Inspect() != expected {
+				t.Errorf("%s: expected %s, got %s", tt.input, expected, evaluated.Inspect())
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"sum = 0\nfor i in 1..10 { sum = sum + i }\nsum", 55},
+		{"sum = 0\nfor i in 0..<10 step 3 { sum = sum + i }\nsum", 18},
+		{"sum = 0\nfor i in 1..3 { sum = sum + i }; sum", 6},
+		{"n = 0\nfor i, v in [5, 6, 7] { n = n + i * v }\nn", 20},
+		{"n = 0\nfor c in \"abc\" { n = n + 1 }\nn", 3},
+		{`out = ""
+for k, v in {"b": 2, "a": 1} { out = out + k + str(v) }
+out`, "b2a1"},
+		{"for x in 1..3 { inner = x }\ninner", "identifier not found: inner"},
+		{"x = 10\nfor x in 1..3 { }\nx", 10},
+		{`f = meth {
+	for i in 0..1000000000000 {
+		if i == 3 { (i)-> }
+	}
+	(0)->
+}
+f()`, 3},
+		{`g = 0
+for i in 1..3 { if i == 2 { g = meth { i } } }
+g()`, 2},
+		{"for x in 5 { x }", "cannot iterate over INTEGER"},
+		{"for x in 1..3 { x / 0 }", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("expected error %q, got %q", expected, result.Message)
+				}
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("expected %q, got %q", expected, result.Value)
+				}
+			default:
+				t.Errorf("expected %q, got %T (%+v)", expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match 1 { 1 -> "one", 2 -> "two" }`, "one"},
+		{`match 2 { 1 -> "one", _ -> "other" }`, "other"},
+		{`match "b" { "a" -> 1, "b" -> 2 }`, 2},
+		{`match true { false -> 0, true -> 1 }`, 1},
+		{`match -3 { -3 -> 1, _ -> 0 }`, 1},
+		{`match 2.0 { 2 -> "int", _ -> "other" }`, "int"},
+		{`match 7 { 0..<5 -> "low", 5..9 -> "mid", _ -> "high" }`, "mid"},
+		{`match 4 { 0..10 step 2 -> "even", _ -> "odd" }`, "even"},
+		{`match 5 { n -> n * 2 }`, 10},
+		{`match 15 {
+	n int if n > 10 -> "big"
+	n int -> "small"
+}`, "big"},
+		{`match "x" { _ int -> "int", s string -> s + s }`, "xx"},
+		{`match 1.5 { _ int -> 1, _ float -> 2 }`, 2},
+		{`match null { _ int -> 1, n null -> 2 }`, 2},
+		{"null is null", true},
+		{"0 is null", false},
+		{"meth f: a null -> null { a }\nf(null)", nil},
+		{"meth f: a null { a }\nf(0)", "argument 1 to f: want null, got INTEGER"},
+		{`match [1, 2, 3] { [a, b] -> 0, [a, b, c] -> a + b + c }`, 6},
+		{`match [1, 2, 3] { [1, ...rest] -> len(rest) }`, 2},
+		{`match [] { [first, ...rest] -> 1, [] -> 0 }`, 0},
+		{`[first, ...rest] = [1, 2, 3]
+match [first, ...rest] { [a, ...r] -> a + len(r) }`, 3},
+		{`match [[1, 2], 3] { [[_, x], y] -> x + y }`, 5},
+		{`match {"name": "Jet", "v": 2} { {"v": 1} -> "old", {"name": n, "v": 2} -> n }`, "Jet"},
+		{`match {"a": 1} { {"b": _} -> 1, _ map -> 2 }`, 2},
+		{`x = match 3 { n if n % 2 == 0 -> "even", _ -> "odd" }; x`, "odd"},
+		{`match 3 { 1 -> "one" }`, "no match arm for 3"},
+		{`match 3 { _ widget -> 1 }`, "unknown type: widget"},
+		{`match 3 { n if n / 0 -> 1 }`, "division by zero"},
+		{`match 3 { n -> m }; n`, "identifier not found: m"},
+		{`match 3 { n -> n }; n`, "identifier not found: n"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, result.Value)
+				}
+			default:
+				t.Errorf("%s: expected %q, got %T (%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestComprehensions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[x * 2 for x in [1, 2, 3]]", "[2, 4, 6]"},
+		{"[x for x in [3, -1, 4, -2] if x > 0]", "[3, 4]"},
+		{"[i * v for i, v in [5, 6, 7]]", "[0, 6, 14]"},
+		{"[x for x in 1..<4]", "[1, 2, 3]"},
+		{`[c + c for c in "ab"]`, `[aa, bb]`},
+		{"[x for x in []]", "[]"},
+		{"[[x * y for y in 1..2] for x in 1..2]", "[[1, 2], [2, 4]]"},
+		{`[k for k, v in {"b": 2, "a": 1}]`, "[b, a]"},
+		{`[k for k, v in {2: "b", 10: "c", 1: "a"}]`, "[2, 10, 1]"},
+		{`{x: x * 2 for x in [3, 1, 2, 7, 5]}`, "{3: 6, 1: 2, 2: 4, 7: 14, 5: 10}"},
+		{`{x % 2: x for x in [1, 2, 3]}`, "{1: 3, 0: 2}"},
+		{`{"b": 2, "a": 1, "b": 3}`, "{b: 3, a: 1}"},
+		{`a = {"z": 1}; {...a, "y": 2, "z": 3}`, "{z: 3, y: 2}"},
+		{`g = meth { for i in 1..5 { yield i } }
+[x for x in g() if x % 2 == 1]`, "[1, 3, 5]"},
+		{`m = {k: v * 10 for k, v in {"a": 1, "b": 2}}
+m["a"] + m["b"]`, 30},
+		{`m = {x % 3: x for x in 1..10}
+m[0] + m[1] + m[2]`, 27},
+		{`{x % 3: x for x in 1..10}[1]`, 10},
+		{`{x: x for x in [1, 2] if x > 1}[2]`, 2},
+		{`{x: x for x in [1, 2] if x > 1}[1]`, nil},
+		{"x = 10\n[x for x in 1..3]\nx", 10},
+		{"[y for y in 1..3]\ny", "identifier not found: y"},
+		{"n = 2\n[x * n for x in 1..3]", "[2, 4, 6]"},
+		{"[x for x in 5]", "cannot iterate over INTEGER"},
+		{"[x / 0 for x in 1..3]", "division by zero"},
+		{"[x for x in 1..3 if x / 0]", "division by zero"},
+		{"{[x]: x for x in 1..3}", "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			case *object.Array, *object.HashMap:
+				if result.Inspect() != expected {
+					t.Errorf("%s: expected %s, got %s", tt.input, expected, result.Inspect())
+				}
+			default:
+				t.Errorf("%s: expected %q, got %T (%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[a, b] = [1, 2]; a * 10 + b", 12},
+		{"[a, b, ...rest] = [1, 2, 3, 4]; len(rest)", 2},
+		{"[a, b, ...rest] = [1, 2, 3, 4]; rest[1]", 4},
+		{"[a, ...rest] = [1]; len(rest)", 0},
+		{"[a, [b, c]] = [1, [2, 3]]; a + b + c", 6},
+		{"[a, b] = [1]; b", nil},
+		{`{name, age} = {"name": "Jet", "age": 3}; name + str(age)`, "Jet3"},
+		{`{"full name": n} = {"full name": "Jet Lang"}; n`, "Jet Lang"},
+		{`{name, ...rest} = {"name": "a", "x": 1, "y": 2}; rest["x"] + rest["y"]`, 3},
+		{`{name, ...rest} = {"name": "a", "x": 1}; rest["name"]`, nil},
+		{`{missing} = {"name": "a"}; missing`, nil},
+		{`{"pos": [x, y]} = {"pos": [3, 4]}; x * y`, 12},
+		{"x = 1\nf = meth { [x, y] = [5, 6] }\nf()\nx", 1},
+		{"x = 1\nfor i in 1..2 { [x, y] = [i, 0] }\nx", 2},
+		{"n = 0\nfor [a, b] in [[1, 2], [3, 4]] { n = n + a * b }\nn", 14},
+		{`out = ""
+for i, {name} in [{"name": "a"}, {"name": "b"}] { out = out + str(i) + name }
+out`, "0a1b"},
+		{"[a + b for [a, b] in [[1, 2], [3, 4]]][1]", 7},
+		{"f = meth: [a, b] { a - b }\nf([5, 3])", 2},
+		{`f = meth: {x, y}, scale = 1 { (x + y) * scale }
+f({"x": 1, "y": 2}, scale: 10)`, 30},
+		{"[a, b] = 5", "cannot destructure INTEGER as an array"},
+		{"{a} = [1]", "cannot destructure ARRAY as a map"},
+		{"f = meth: [a] { a }\nf(1)", "cannot destructure INTEGER as an array"},
+		{"for [a] in [1] { a }", "cannot destructure INTEGER as an array"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, result.Value)
+				}
+			default:
+				t.Errorf("%s: expected %q, got %T (%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestStrictDestructuring(t *testing.T) {
+	StrictDestructuring = true
+	defer func() { StrictDestructuring = false }()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[a, b] = [1]", "not enough elements to destructure: want 2, got 1"},
+		{`{name, age} = {"name": "a"}`, "missing key age to destructure"},
+		{`{"a": [x, y]} = {"a": [1]}`, "not enough elements to destructure: want 2, got 1"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: expected an error", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("%s: expected error %q, got %q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+
+	testIntegerObject(t, testEval("[a, ...rest] = [1]; a"), 1)
+	testIntegerObject(t, testEval("[a] = [1, 2]; a"), 1)
+}
+
+func TestDeferStatements(t *testing.T) {
+	var log []string
+	builtins["record"] = &object.BuiltIn{Method: func(args ...object.Object) object.Object {
+		log = append(log, args[0].Inspect())
+		return nil
+	}}
+	defer delete(builtins, "record")
+
+	tests := []struct {
+		input    string
+		log      string
+		expected interface{}
+	}{
+		{`f = meth { defer record("a"); defer record("b"); record("body"); 1 }
+f()`, "body b a", 1},
+		{`f = meth { defer record("done"); (2)->; record("unreached") }
+f()`, "done", 2},
+		{`f = meth { defer record("done"); 1 / 0 }
+f()`, "done", "division by zero"},
+		{`f = meth { x = "early"; defer record(x); x = "late" }
+f()`, "early", nil},
+		{`f = meth { for i in 1..3 { defer record(str(i)) } }
+f()`, "3 2 1", nil},
+		{`f = meth: n { defer record(str(n)); if n > 0 { f(n - 1) } }
+f(2)`, "0 1 2", nil},
+		{`g = meth { defer record("closed"); for i in 0..1000000000000 { yield i } }
+h = meth { for v in g() { if v == 2 { (v)-> } } }
+h()`, "closed", 2},
+		{`g = meth { defer record("finished"); yield 1 }
+array(g())`, "finished", nil},
+		{`f = meth { defer missing(); 1 }
+f()`, "", "identifier not found: missing"},
+		{`boom = meth { 1 / 0 }
+f = meth { defer boom(); defer record("still runs"); 1 }
+f()`, "still runs", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		log = nil
+		evaluated := testEval(tt.input)
+		if got := strings.Join(log, " "); got != tt.log {
+			t.Errorf("%s: expected calls %q, got %q", tt.input, tt.log, got)
+		}
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: expected error %q, got %T (%+v)", tt.input, expected, evaluated, evaluated)
+			} else if errObj.Message != expected {
+				t.Errorf("%s: expected error %q, got %q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestNullExpressions(t *testing.T) {
+	config := `config = {"db": {"host": "localhost", "ports": [5432]}, "debug": false}
+`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null", nil},
+		{"null == null", true},
+		{"1 == null", false},
+		{"null != 1", true},
+		{`null == false`, false},
+		{"!null", true},
+		{"null + 1", "type mismatch: NULL + INTEGER"},
+		{"-null", "unknown operator: -NULL"},
+		{"null[0]", "index operator not supported: NULL"},
+		{config + "config.db.host", "localhost"},
+		{config + "config.missing", nil},
+		{config + "config.missing?.host", nil},
+		{config + "config.missing.host", "cannot read host of NULL"},
+		{config + `config?["db"]?["ports"]?[0]`, 5432},
+		{config + `config["cache"]?["ports"]?[0]`, nil},
+		{config + "config.cache?.ports?[0:1]", nil},
+		{config + `config.cache?.host ?? "default"`, "default"},
+		{config + `config.db?.host ?? "default"`, "localhost"},
+		{config + "config.debug ?? true", false},
+		{"null?[1 / 0]", nil},
+		{"null ?? null ?? 3", 3},
+		{"1 ?? 1 / 0", 1},
+		{"null ?? 1 / 0", "division by zero"},
+		{"1 ?? 2 == 2", false},
+		{"null ?? 2 == 2", true},
+		{"5.a", "cannot read a of INTEGER"},
+		{"match null { null -> 1, _ -> 2 }", 1},
+		{"match null { _ bool -> 1, _ -> 2 }", 2},
+		{"match 0 { null -> 1, _ -> 2 }", 2},
+		{"f = meth { }\nf() ?? 7", 7},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case nil:
+			testNullObject(t, evaluated)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, result.Value)
+				}
+			default:
+				t.Errorf("%s: expected %q, got %T (%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestDescriptorContracts(t *testing.T) {
+	shapes := `describe Shape {
+	meth area
+	meth scale: by
+}
+describe Named: Name {
+	meth intro { (Name + " is a shape")-> }
+}
+describe Sized: Side {}
+object Square: Shape, Named, Sized {
+	meth area { (Side * Side)-> }
+	meth scale: by { (Square(Name, Side * by))-> }
+}
+object Label: Named {}
+sq = Square("box", 3)
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{shapes + "sq.area()", 9},
+		{shapes + "sq.scale(2).area()", 36},
+		{shapes + "sq.intro()", "box is a shape"},
+		{shapes + "sq is Shape", true},
+		{"describe D: N {}; object O: D {}; O(2).N", 2},
+		{shapes + "sq is Named", true},
+		{shapes + "sq is Square", true},
+		{shapes + "sq is Label", false},
+		{shapes + `Label("x") is Shape`, false},
+		{shapes + "5 is Shape", false},
+		{shapes + "5 is int", true},
+		{shapes + "sq is Nothing", "unknown type: Nothing"},
+		{shapes + "descriptors(sq)", `[describe Shape, describe Named, describe Sized]`},
+		{shapes + "named = descriptors(sq)[1]\nsq is named", true},
+		{"describe K { const C = 1 }\nobject O: K {}\ndescriptors(O())[0].C", 1},
+		{shapes + "descriptors(5)", "argument to `descriptors` must be an object, got INTEGER"},
+		{shapes + "match sq { s Shape -> s.area(), _ -> 0 }", 9},
+		{shapes + "object Circle: Shape { meth area { 1 } }", "Circle must provide method scale required by Shape"},
+		{shapes + "object Circle: Shape { meth area: r { 1 }\nmeth scale: by { 1 } }", "Circle.area must take 0 parameters to satisfy Shape, got 1"},
+		{shapes + "object Circle: Shape { meth area: r = 1 { r }\nmeth scale: by, around = 0 { 1 } }\nCircle().area()", 1},
+		{shapes + "object Circle: Shape { meth area: r, s = 1 { 1 }\nmeth scale: by { 1 } }", "Circle.area must take 0 parameters to satisfy Shape, got 1 to 2"},
+		{shapes + "describe Area { meth area { 1 } }\ndescribe Scale { meth scale: by { 1 } }\nobject C: Shape, Area, Scale {}\nC().area()", 1},
+		{"describe D { meth a\nmeth a { 1 } }", "D declares a more than once"},
+		{"describe A { const Z = 1\nconst Y = 1 }\ndescribe B { const Z = 2\nconst Y = 2 }\nobject O: A, B {}",
+			"Y is declared by both A and B"},
+		{"describe A { meth b { 1 }\nmeth a { 1 } }\ndescribe B { meth b { 2 }\nmeth a { 2 } }\nobject O: A, B {}",
+			"O gets a from A and B; overload it to choose one"},
+		{"describe A { overload +: o { 1 }\nmeth z { 1 } }\ndescribe B { overload +: o { 2 }\nmeth z { 2 } }\nobject O: A, B {}",
+			"O gets + from more than one descriptor; overload it to choose one"},
+		{`taxed = meth {
+	rate = 3
+	describe Taxed: Price { meth total { (Price * rate)-> } }
+	(Taxed)->
+}
+Taxed = taxed()
+object Item: Taxed {}
+Item(10).total()`, 30},
+		{`rate = 2
+describe Taxed: Price { meth total { (Price * rate)-> } }
+f = meth {
+	rate = 100
+	object Item: Taxed { meth own { (rate)-> } }
+	([Item(10).total(), Item(10).own()])->
+}
+f()`, "[20, 100]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			default:
+				if got := evaluated.Inspect(); got != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestObjectInitialisation(t *testing.T) {
+	var log []string
+	builtins["record"] = &object.BuiltIn{Method: func(args ...object.Object) object.Object {
+		log = append(log, args[0].Inspect())
+		return nil
+	}}
+	defer delete(builtins, "record")
+
+	jets := `describe Vehicle: Seats {
+	Wheels = 4
+	const Material = "Metal"
+	validate Seats: n { (n > 0)-> }
+	meth init { record("Vehicle") }
+}
+describe Jet: Name, TopSpeed {
+	validate Name: n { if len(n) == 0 { error("a jet needs a name") }; true }
+	meth init {
+		record("Jet")
+		if TopSpeed > 1000 { Jet.TopSpeed = 1000 }
+	}
+}
+object FighterJet: Vehicle, Jet {
+	meth init { record("FighterJet") }
+	meth refit: seats { Vehicle.Seats = seats }
+}
+`
+	tests := []struct {
+		input    string
+		log      string
+		expected string
+	}{
+		{jets + `FighterJet(2, "Falcon", 5000)`, "Vehicle Jet FighterJet", "FighterJet{Seats: 2, Wheels: 4, Name: Falcon, TopSpeed: 1000}"},
+		{jets + `FighterJet(0, "Falcon", 5)`, "Vehicle Jet FighterJet", "invalid Seats for FighterJet: 0"},
+		{jets + `FighterJet(1, "", 5)`, "Vehicle Jet FighterJet", "a jet needs a name"},
+		{jets + `f = FighterJet(1, "a", 5); f.Wheels = 3; f.Wheels`, "Vehicle Jet FighterJet", "3"},
+		{jets + `f = FighterJet(1, "a", 5); f.refit(6); f.Seats`, "Vehicle Jet FighterJet", "6"},
+		{jets + `f = FighterJet(1, "a", 5); f.refit(0)`, "Vehicle Jet FighterJet", "invalid Seats for FighterJet: 0"},
+		{jets + `f = FighterJet(1, "a", 5); f.Seats = -1`, "Vehicle Jet FighterJet", "invalid Seats for FighterJet: -1"},
+		{jets + `f = FighterJet(1, "a", 5); f.Material = 3`, "Vehicle Jet FighterJet", "cannot assign to constant Material"},
+		{jets + `f = FighterJet(1, "a", 5); f.Fuel = 3`, "Vehicle Jet FighterJet", "FighterJet has no property Fuel"},
+		{`describe D: A { meth init { error("no") } }
+object O: D { meth init { record("unreached") } }
+O(1)`, "", "no"},
+		{`describe D: A { meth init: x { 1 } }`, "", "init in D takes no parameters, got 1"},
+		{`describe D: A { validate B: b { true } }`, "", "D has no property B to validate"},
+		{`describe D: A { validate A: a { true } }
+object O: D { validate A: a { true } }`, "", "A is validated more than once"},
+		{`describe D: A {}
+object O: D { B = 1
+validate B: b { (b < 10)-> } }
+o = O(1)
+o.B = 20`, "", "invalid B for O: 20"},
+		{"m = {}\nm.a = 1", "", "cannot set a of HASH"},
+		{`object C { Count = 0
+meth inc { Count = Count + 1 } }
+c = C()
+c.inc(); c.inc()
+c.Count`, "", "2"},
+		{`describe Named: Name {}
+object Tag: Named { meth init { Name = Name + "!" } }
+Tag("new").Name`, "", "new!"},
+		{`object C { Count = 0
+validate Count: n { (n < 2)-> }
+meth inc { Count = Count + 1 } }
+c = C()
+c.inc(); c.inc()`, "", "invalid Count for C: 2"},
+		{`object C { const Max = 1
+meth grow { Max = 2 } }
+C().grow()`, "", "cannot assign to constant Max"},
+		{`object C { Count = 0
+meth set: Count { Count = 5 } }
+c = C()
+c.set(1)
+c.Count`, "", "0"},
+		{`object C { Count = 0
+meth reset { [Count, x] = [7, 1] } }
+c = C()
+c.reset()
+c.Count`, "", "7"},
+	}
+
+	for _, tt := range tests {
+		log = nil
+		evaluated := testEval(tt.input)
+		if got := strings.Join(log, " "); got != tt.log {
+			t.Errorf("%s: expected calls %q, got %q", tt.input, tt.log, got)
+		}
+		var got string
+		if errObj, ok := evaluated.(*object.Error); ok {
+			got = errObj.Message
+		} else if evaluated != nil {
+			got = evaluated.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	colours := "enum Colour { Red, Green, Blue }\nenum Size { Small, Large }\n"
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{colours + "Colour.Green", "Colour.Green"},
+		{colours + "Colour.Green.Name", "Green"},
+		{colours + "Colour.Blue.Ordinal", 2},
+		{colours + "Colour", "enum Colour"},
+		{"enum Size { Small }; Size.Small.Ordinal", 0},
+		{colours + "Colour.Red == Colour.Red", true},
+		{colours + "Colour.Red != Colour.Blue", true},
+		{colours + "Colour.Red < Colour.Blue", true},
+		{colours + "Colour.Green >= Colour.Blue", false},
+		{colours + "Colour.Red == null", false},
+		{colours + "Colour.Red == Size.Small", false},
+		{colours + "Colour.Red != Size.Small", true},
+		{colours + "Colour.Red < Size.Small", "type mismatch: Colour < Size"},
+		{colours + "Old = Colour\nenum Colour { Red }\nOld.Red == Colour.Red", false},
+		{colours + "Old = Colour\nenum Colour { Red }\nOld.Red <= Colour.Red", "type mismatch: Colour <= Colour"},
+		{colours + "Colour.Red + Colour.Blue", "unknown operator: Colour + Colour"},
+		{colours + "Colour.Purple", "Colour has no member Purple"},
+		{colours + "Colour.Red.Hue", "cannot read Hue of Colour.Red"},
+		{colours + "len(Colour)", 3},
+		{colours + "[c.Name for c in Colour]", "[Red, Green, Blue]"},
+		{colours + "{Colour.Red: 1, Colour.Blue: 2}[Colour.Blue]", 2},
+		{colours + "{Colour.Red: 1}[Size.Small]", nil},
+		{colours + "match Colour.Green { Colour.Red -> 1, Colour.Green -> 2, _ -> 3 }", 2},
+		{colours + "match Colour.Green { s Size -> 1, c Colour -> c.Ordinal, _ -> 3 }", 1},
+		{colours + "Colour.Blue is Colour", true},
+		{colours + `str(Colour.Red)`, "Colour.Red"},
+		{"enum INTEGER { A }\nINTEGER.A + 1", "type mismatch: INTEGER + INTEGER"},
+		{"enum INTEGER { A }\n{0: \"zero\"}[INTEGER.A]", nil},
+		{"make = meth {\nenum C { A }\n(C.A)->\n}\na = make()\n[{a: 1}[make()], {a: 1}[a]]", "[null, 1]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case nil:
+			testNullObject(t, evaluated)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			default:
+				if got := evaluated.Inspect(); got != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	jets := `describe Jet: Name string, TopSpeed int {
+	Wheels int = 4
+	meth boost: by int -> int { (TopSpeed * by)-> }
+}
+object Plane: Jet {}
+enum Colour { Red, Green }
+`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"meth area: w int, h int -> int { w * h }\narea(2, 3)", 6},
+		{"meth area: w int, h int -> int { w * h }\narea(2, \"3\")", "argument 2 to area: want int, got STRING"},
+		{"meth area: w int, h int -> int { w * h }\narea(h: 2, w: 1.5)", "argument 1 to area: want int, got FLOAT"},
+		{"meth half: n int -> int { n / 2.0 }\nhalf(3)", "return value of half: want int, got FLOAT"},
+		{"meth nothing -> int { }\nnothing()", "return value of nothing: want int, got NULL"},
+		{"f = meth: x string { x }\nf(1)", "argument 1 to method: want string, got INTEGER"},
+		{"meth f: x int = \"a\" { x }\nf()", "argument 1 to f: want int, got STRING"},
+		{"meth f: x number { x }\nf(1)", "unknown type: number"},
+		{"meth f: n int -> generator { yield n }\nnext(f(4))", 4},
+		{"meth f: x int { 1 / 0 }\nf(1)", "division by zero"},
+		{jets + `Plane("Falcon", 100).boost(2)`, 200},
+		{jets + `Plane("Falcon", "fast")`, "property TopSpeed of Plane: want int, got STRING"},
+		{jets + `p = Plane("Falcon", 100); p.Wheels = "four"`, "property Wheels of Plane: want int, got STRING"},
+		{jets + `Plane("Falcon", 100).boost(2.5)`, "argument 1 to boost: want int, got FLOAT"},
+		{jets + "meth paint: p Plane, c Colour { c.Name }\npaint(Plane(\"a\", 1), Colour.Red)", "Red"},
+		{jets + "meth paint: p Jet { p.Name }\npaint(5)", "argument 1 to paint: want Jet, got INTEGER"},
+		{"describe D { X int = \"x\" }", "property X of D: want int, got STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			default:
+				if got := evaluated.Inspect(); got != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, got)
+				}
+			}
+		}
+	}
+
+	evaluated := testEval("meth area: w int, h int { w * h }\n\narea(2, \"3\")")
+	if err, ok := evaluated.(*object.Error); !ok || err.Line != 3 || err.Col != 5 {
+		t.Errorf("expected error at line 3, col 5, got %+v", evaluated)
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	money := `describe Money: Amount {
+	overload ==: other { (Amount == other.Amount)-> }
+	overload <: other { (Amount < other.Amount)-> }
+}
+object Pounds: Money {
+	overload +: other { (Pounds(Amount + other.Amount))-> }
+}
+a = Pounds(5)
+b = Pounds(3)
+`
+	vector := `describe Vector: Items {
+	overload []: i { (Items[i])-> }
+	overload len { (len(Items))-> }
+	overload iter { (Items)-> }
+}
+object V: Vector {}
+v = V([1, 2, 3])
+`
+	jet := `describe Vehicle: Seats {
+	const Material = "Metal"
+	Wheels = 4
+}
+describe Jet: Name, TopSpeed {
+	meth speedBoost { (TopSpeed * 2)-> }
+}
+object FighterJet: Vehicle, Jet {
+	overload speedBoost { (Jet.TopSpeed * 4)-> }
+}
+f = FighterJet(2, "Falcon", 100)
+`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{money + "a + b", "Pounds{Amount: 8}"},
+		{money + "(a + b).Amount", 8},
+		{money + "a == Pounds(5)", true},
+		{money + "a != b", true},
+		{money + "a < b", false},
+		{money + "a > b", true},
+		{money + "a <= b", false},
+		{money + "a >= Pounds(5)", true},
+		{money + "sort([a, b, Pounds(4)])", "[Pounds{Amount: 3}, Pounds{Amount: 4}, Pounds{Amount: 5}]"},
+		{money + "a - b", "unknown operator: Pounds - Pounds"},
+		{money + "a * 2", "type mismatch: Pounds * INTEGER"},
+		{vector + "v[1]", 2},
+		{vector + "len(v)", 3},
+		{vector + "[x * 2 for x in v]", "[2, 4, 6]"},
+		{jet + "f.speedBoost()", 400},
+		{jet + "f.Wheels", 4},
+		{jet + "f.Jet.TopSpeed", 100},
+		{jet + "Vehicle.Material", "Metal"},
+		{jet + "f.Vehicle.Name", "Vehicle does not describe Name"},
+		{jet + "f.Fuel", "FighterJet has no property Fuel"},
+		{jet + "FighterJet(1, 2)", "wrong number of arguments to FighterJet: want 3, got 2"},
+		{jet + `FighterJet(Seats: 1, Name: "x")`, "missing argument for FighterJet.TopSpeed"},
+		{jet + `FighterJet(1, "x", TopSpeed: 3).speedBoost()`, 12},
+		{jet + "object Bad: Jet { meth speedBoost { 1 } }", "Bad already has speedBoost from Jet; use overload to replace it"},
+		{jet + "object Bad: Jet { overload refuel { 1 } }", "Bad has no method refuel to overload"},
+		{"describe A: X {}\ndescribe B: X {}\nobject C: A, B {}", "X is declared by both A and B"},
+		{"object C: Nope {}", "Nope is not a descriptor"},
+		{"object STRING {}\nSTRING() + \"a\"", "type mismatch: STRING + STRING; convert with str(STRING{}) to concatenate"},
+		{"object INTEGER {}\nINTEGER() * 2", "type mismatch: INTEGER * INTEGER"},
+		{"sort([3, 1, 2])", "[1, 2, 3]"},
+		{`sort([1, "a"])`, "type mismatch: STRING < INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			default:
+				if got := evaluated.Inspect(); got != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try { 1 } catch { 2 }", 1},
+		{"try { 1 / 0 } catch { 2 }", 2},
+		{`try { 1 / 0 } catch err { err["message"] }`, "division by zero"},
+		{`try { 1 + "a" } catch err { err["message"] }`, "type mismatch: INTEGER + STRING; convert with str(1) to concatenate"},
+		{`try { len(1) } catch err { err["message"] }`, "argument to `len` not supported, got INTEGER"},
+		{`try { "ab" * 9223372036854775807 } catch err { err["col"] }`, 12},
+		{`try { error("bad record") } catch err { err["message"] }`, "bad record"},
+		{`try { error(42) } catch err { err["message"] }`, "42"},
+		{"meth down: n { if n == 0 { 0 } else { down(n - 1) + 1 } }\ntry { down(1000000) } catch { 0 }\ndown(5000)", 5000},
+		{`error("bad " + "record")`, "bad record"},
+		{`f = meth: x { if x < 0 { error("negative: " + str(x)) }; x }
+try { f(-1) } catch err { err["message"] + " " + err["stack"][0] }`, "negative: -1 f at line 2, col 8"},
+		{"try {\n\n  1 / 0 } catch err { err[\"line\"] * 100 + err[\"col\"] }", 305},
+		{`inner = meth { 1 / 0 }
+outer = meth { inner() }
+try { outer() } catch err { err["stack"][0] + "; " + err["stack"][1] }`,
+			"inner at line 2, col 21; outer at line 3, col 12"},
+		{`f = meth { try { (1)-> } catch { 2 }; 3 }
+f()`, 1},
+		{`total = 0
+for x in [1, 0, 2] {
+	try { total = total + 10 / x } catch { total = total + 100 }
+}
+total`, 115},
+		{`try { 1 / 0 } catch err { 1 }
+err`, "identifier not found: err"},
+		{`try { 1 / 0 } catch err { error("wrapped: " + err["message"]) }`, "wrapped: division by zero"},
+		{`try { try { 1 / 0 } catch { x } } catch err { err["message"] }`, "identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, result.Value)
+				}
+			default:
+				t.Errorf("%s: expected %q, got %T (%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`squares = meth: n { for i in 1..n { yield i * i } }
+sum = 0
+for v in squares(3) { sum = sum + v }
+sum`, 14},
+		{`naturals = meth { for i in 0..1000000000000 { yield i } }
+f = meth {
+	for n in naturals() {
+		if n == 5 { (n)-> }
+	}
+}
+f()`, 5},
+		{`g = meth { yield 1; yield 2 }
+len(array(g()))`, 2},
+		{`g = meth { yield "a"; yield "b" }
+it = g()
+next(it) + next(it)`, "ab"},
+		{`g = meth { yield 1 }
+it = g()
+next(it)
+next(it)`, nil},
+		{`g = meth { yield 1; (0)->; yield 2 }
+len(array(g()))`, 1},
+		{`calls = 0
+g = meth { calls = calls + 1; yield 1 }
+it = g()
+calls`, 0},
+		{`evens = meth: xs { for x in xs { if x % 2 == 0 { yield x } } }
+nat = meth: n { for i in 1..n { yield i } }
+out = 0
+for e in evens(nat(10)) { out = out + e }
+out`, 30},
+		{`g = meth { for i in 1..3 { yield i } }
+out = ""
+for i, v in g() { out = out + str(i) + str(v) }
+out`, "011223"},
+		{`g = meth { yield 1; yield 1 / 0 }
+for x in g() { x }`, "division by zero"},
+		{`g = meth { yield 1 / 0 }
+next(g())`, "division by zero"},
+		{`g = meth { yield 1; yield 1 / 0 }
+array(g())`, "division by zero"},
+		{`array(5)`, "argument to `array` not supported, got INTEGER"},
+		{`g = meth { yield 1 }
+match g() { _ generator -> "gen", _ -> "other" }`, "gen"},
+		{`next(5)`, "argument to `next` not supported, got INTEGER"},
+		{`g = meth { yield 1; yield 2 }
+gen = g()
+next(gen)
+close(gen)
+next(gen)`, nil},
+		{`gen = meth { close(g); yield 1 }
+g = gen()
+array(g)[0]`, 1},
+		{`close(5)`, "argument to `close` not supported, got INTEGER"},
+		{`g = meth { for i in 0..100 { try { yield i } catch { } } }
+gen = g()
+next(gen)
+close(gen)
+next(gen)`, nil},
+		{`g = meth { for i in 0..100 { try { yield i } catch { } } }
+f = meth { for x in g() { if x == 2 { (x)-> } } }
+f()`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, result.Value)
+				}
+			default:
+				t.Errorf("%s: expected %q, got %T (%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestGeneratorsDoNotLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	testEval(`naturals = meth { for i in 0..1000000000000 { yield i } }
+f = meth {
+	for n in naturals() {
+		if n == 3 { (n)-> }
+	}
+}
+for i in 1..50 { f() }`)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked: %d before, %d after", before, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStoredGeneratorsDoNotLeak(t *testing.T) {
+	tests := []string{
+		// A generator stored where its own body can reach it is closed
+		// explicitly
+		`naturals = meth { for i in 0..1000000000000 { yield i } }
+for i in 1..50 { g = naturals(); next(g); next(g); close(g) }`,
+		// One kept in a method's scope is closed once it's unreachable and
+		// the interpreter reaches a safe point
+		`naturals = meth { for i in 0..1000000000000 { yield i } }
+f = meth { g = naturals(); next(g); next(g) }
+for i in 1..50 { f() }`,
+	}
+
+	for _, input := range tests {
+		before := runtime.NumGoroutine()
+		testEval(input)
+
+		deadline := time.Now().Add(2 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: goroutines leaked: %d before, %d after", input, before, runtime.NumGoroutine())
+			}
+			runtime.GC()
+			object.CloseAbandoned()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestAbandonedGeneratorsCloseOnInterpreter(t *testing.T) {
+	env := object.NewEnvironment()
+	run := func(input string) object.Object {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		resolver.Resolve(program)
+		return Eval(program, env)
+	}
+	setup := run(`describe Counted: Count { meth inc { Count = Count + 1 } }
+object Counter: Counted {}
+c = Counter(0)
+g = meth { defer c.inc(); for i in 0..1000000000000 { yield i } }
+f = meth { it = g(); next(it) }
+for i in 1..2000 { f(); c.inc() }`)
+	if isError(setup) {
+		t.Fatalf("setup failed: %s", setup.Inspect())
+	}
+
+	// Each generator's deferred inc runs once it's closed, which only
+	// happens on this goroutine
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		count := run("c.Count")
+		if count.Inspect() == "4000" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected count 4000, got %s", count.Inspect())
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 	evaluated := testEval(input)
@@ -306,6 +1725,57 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"ab" < "b"`, true},
+		{`"a" <= "a"`, true},
+		{`"b" > "a"`, true},
+		{`"a" >= "b"`, false},
+		{`"Z" < "a"`, true},
+		{`"é" > "z"`, true},
+		{`"ell" in "Hello"`, true},
+		{`"" in "Hello"`, true},
+		{`"hello" in "Hello"`, false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStringRepetition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"ab" * 3`, "ababab"},
+		{`3 * "ab"`, "ababab"},
+		{`"ab" * 0`, ""},
+		{`"" * 9223372036854775807`, ""},
+		{`"-" * 2 + "x"`, "--x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String, got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value, expected %q, got %q", tt.expected, str.Value)
+		}
+	}
+}
+
 func TestBuiltInMethods(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -316,6 +1786,11 @@ func TestBuiltInMethods(t *testing.T) {
 		{`len("hello world")`, 11},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "len: incorrect argument count; want 1, got 2"},
+		{`len(str(1234))`, 4},
+		{`len(str("four"))`, 4},
+		{`str()`, "wrong number of arguments, want 1, got 0"},
+		{`len("a\tb\\")`, 4},
+		{"len(`a\\tb\n`)", 5},
 	}
 
 	for _, tt := range tests {
@@ -368,7 +1843,9 @@ func TestArrayIndexExpressiosn(t *testing.T) {
 		{"myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2]", 6},
 		{"myArray = [1, 2, 3]; i = myArray[0]; myArray[i]", 2},
 		{"[1, 2, 3][3]", nil},
-		{"[1, 2, 3][-1]", nil},
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", nil},
 	}
 
 	for _, tt := range tests {
@@ -382,6 +1859,56 @@ func TestArrayIndexExpressiosn(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4][1:10]", "[2, 3, 4]"},
+		{"[1, 2, 3, 4][-10:1]", "[1]"},
+		{"[1, 2, 3, 4][3:1]", "[]"},
+		{"[1, 2, 3, 4][99999999999999999999:]", "[]"},
+		{`"Hello"[1:3]`, "el"},
+		{`"Hello"[-3:]`, "llo"},
+		{`"héllo, 世界"[1:2]`, "é"},
+		{`"héllo, 世界"[-2:]`, "世界"},
+		{`"abc"[5:]`, ""},
+		{`"Hello"[0]`, "H"},
+		{`"Hello"[-1]`, "o"},
+		{`"世界"[1]`, "界"},
+		{`s = "héllo"; s[len(s) - 1]`, "o"},
+		{`s = "héllo"; s[len(s) - 4:len(s)]`, "éllo"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		var got string
+		switch result := evaluated.(type) {
+		case *object.String:
+			got = result.Value
+		case *object.Array:
+			got = result.Inspect()
+		default:
+			t.Errorf("%s: expected String or Array, got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	nullTests := []string{`"abc"[3]`, `"abc"[-4]`, `""[0]`}
+	for _, input := range nullTests {
+		testNullObject(t, testEval(input))
+	}
+}
+
 func TestHashMaps(t *testing.T) {
 	input := `two = "two"
 			{
@@ -0,0 +1,66 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/object"
+	"math"
+	"math/big"
+)
+
+// evalFloatInfixExpr handles arithmetic where at least one side is a
+// float. The other side may be an int, which is converted first.
+func evalFloatInfixExpr(op string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+	switch op {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "<=":
+		return nativeBoolToBooleanObj(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObj(leftVal >= rightVal)
+	case "<":
+		return nativeBoolToBooleanObj(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObj(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObj(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObj(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", typeName(left), op, typeName(right))
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch n := obj.(type) {
+	case *object.Float:
+		return n.Value
+	case *object.Integer:
+		return float64(n.Value)
+	case *object.BigInteger:
+		f, _ := new(big.Float).SetInt(n.Value).Float64()
+		return f
+	default:
+		return math.NaN()
+	}
+}
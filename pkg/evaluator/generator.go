@@ -0,0 +1,37 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+// newGenerator returns a generator that runs method's body in env the
+// first time a value is asked for. Making one is also when abandoned
+// generators are closed, so that a loop making many doesn't pile them up.
+func newGenerator(method *object.Method, env *object.Environment) *object.Generator {
+	object.CloseAbandoned()
+	return object.NewGenerator(func(yield func(object.Object)) (result object.Object) {
+		env.SetYield(yield)
+		// Closing a generator early unwinds its body with a panic, so
+		// deferred calls are run from a Go defer to cover that too.
+		defer func() { result = runDeferred(env, result) }()
+		result = eval(method.Body, env)
+		if !isError(result) {
+			result = nil
+		}
+		return result
+	})
+}
+
+func evalYieldExpression(node *ast.YieldExpression, env *object.Environment) object.Object {
+	yield := env.Yield()
+	if yield == nil {
+		return withPosition(newError("yield outside of a generator"), node.Token)
+	}
+	value := eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	yield(value)
+	return NULL
+}
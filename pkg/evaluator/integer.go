@@ -0,0 +1,274 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+	"math"
+	"math/big"
+)
+
+// OverflowMode selects what integer arithmetic does when a result
+// does not fit in 64 bits.
+type OverflowMode int
+
+const (
+	// PromoteOnOverflow continues with an arbitrary-precision BigInteger.
+	PromoteOnOverflow OverflowMode = iota
+	// ErrorOnOverflow stops with an integer overflow error.
+	ErrorOnOverflow
+)
+
+// IntegerOverflow is the OverflowMode used by the interpreter.
+var IntegerOverflow = PromoteOnOverflow
+
+// maxIntegerBits caps the size of a BigInteger built by *, ** or <<, so
+// that a mistyped operand fails cleanly instead of exhausting memory.
+const maxIntegerBits = 1 << 26
+
+func evalIntLiteral(lit *ast.IntLiteral) object.Object {
+	if lit.Big == nil {
+		return &object.Integer{Value: lit.Value}
+	}
+	if IntegerOverflow == ErrorOnOverflow {
+		return withPosition(newError("integer overflow: %s does not fit in 64 bits", lit.Big), lit.Token)
+	}
+	return object.NewBigInteger(lit.Big)
+}
+
+func evalMinusPrefixOpExpression(right object.Object) object.Object {
+	switch value := right.(type) {
+	case *object.Integer:
+		if value.Value != math.MinInt64 {
+			return &object.Integer{Value: -value.Value}
+		}
+		if IntegerOverflow == ErrorOnOverflow {
+			return newError("integer overflow: -(%d)", value.Value)
+		}
+		return object.NewBigInteger(new(big.Int).Neg(big.NewInt(value.Value)))
+	case *object.BigInteger:
+		return object.NewBigInteger(new(big.Int).Neg(value.Value))
+	case *object.Float:
+		return &object.Float{Value: -value.Value}
+	default:
+		return newError("unknown operator: -%s", typeName(right))
+	}
+}
+
+func evalBitNotPrefixOpExpression(right object.Object) object.Object {
+	switch value := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^value.Value}
+	case *object.BigInteger:
+		return object.NewBigInteger(new(big.Int).Not(value.Value))
+	default:
+		return newError("unknown operator: ~%s", typeName(right))
+	}
+}
+
+// evalIntegerInfixExpr works in int64 where it can, and only falls back
+// to math/big when an operand is already a BigInteger or the int64
+// result would overflow.
+func evalIntegerInfixExpr(op string, left, right object.Object) object.Object {
+	l, leftSmall := left.(*object.Integer)
+	r, rightSmall := right.(*object.Integer)
+	if leftSmall && rightSmall {
+		if result, ok := evalInt64InfixExpr(op, l.Value, r.Value); ok {
+			return result
+		}
+		if IntegerOverflow == ErrorOnOverflow {
+			return newError("integer overflow: %d %s %d", l.Value, op, r.Value)
+		}
+	}
+	return evalBigIntInfixExpr(op, toBigInt(left), toBigInt(right))
+}
+
+// evalInt64InfixExpr reports false if the result of op overflows int64.
+func evalInt64InfixExpr(op string, leftVal, rightVal int64) (object.Object, bool) {
+	switch op {
+	case "+":
+		sum := leftVal + rightVal
+		if (leftVal^sum)&(rightVal^sum) < 0 {
+			return nil, false
+		}
+		return &object.Integer{Value: sum}, true
+	case "-":
+		diff := leftVal - rightVal
+		if (leftVal^rightVal)&(leftVal^diff) < 0 {
+			return nil, false
+		}
+		return &object.Integer{Value: diff}, true
+	case "*":
+		product, ok := mulInt64(leftVal, rightVal)
+		if !ok {
+			return nil, false
+		}
+		return &object.Integer{Value: product}, true
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero"), true
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return nil, false
+		}
+		return &object.Integer{Value: leftVal / rightVal}, true
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero"), true
+		}
+		return &object.Integer{Value: leftVal % rightVal}, true
+	case "**":
+		if rightVal < 0 {
+			return newError("negative exponent: %d", rightVal), true
+		}
+		power, ok := powInt64(leftVal, rightVal)
+		if !ok {
+			return nil, false
+		}
+		return &object.Integer{Value: power}, true
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}, true
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}, true
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}, true
+	case "<<":
+		if rightVal < 0 {
+			return newError("negative shift amount: %d", rightVal), true
+		}
+		if leftVal == 0 {
+			return &object.Integer{Value: 0}, true
+		}
+		if rightVal >= 63 || (leftVal<<rightVal)>>rightVal != leftVal {
+			return nil, false
+		}
+		return &object.Integer{Value: leftVal << rightVal}, true
+	case ">>":
+		if rightVal < 0 {
+			return newError("negative shift amount: %d", rightVal), true
+		}
+		return &object.Integer{Value: leftVal >> rightVal}, true
+	case "<=":
+		return nativeBoolToBooleanObj(leftVal <= rightVal), true
+	case ">=":
+		return nativeBoolToBooleanObj(leftVal >= rightVal), true
+	case "<":
+		return nativeBoolToBooleanObj(leftVal < rightVal), true
+	case ">":
+		return nativeBoolToBooleanObj(leftVal > rightVal), true
+	case "==":
+		return nativeBoolToBooleanObj(leftVal == rightVal), true
+	case "!=":
+		return nativeBoolToBooleanObj(leftVal != rightVal), true
+	default:
+		return newError("unknown operator: %s %s %s", object.INTEGER_OBJ, op, object.INTEGER_OBJ), true
+	}
+}
+
+func evalBigIntInfixExpr(op string, leftVal, rightVal *big.Int) object.Object {
+	switch op {
+	case "+":
+		return object.NewBigInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return object.NewBigInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		if leftVal.BitLen()+rightVal.BitLen() > maxIntegerBits+1 {
+			return newError("result too large: multiplying %d-bit and %d-bit integers", leftVal.BitLen(), rightVal.BitLen())
+		}
+		return object.NewBigInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return object.NewBigInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError("modulo by zero")
+		}
+		return object.NewBigInteger(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		if rightVal.Sign() < 0 {
+			return newError("negative exponent: %s", rightVal)
+		}
+		if !rightVal.IsInt64() {
+			return newError("exponent too large: %s", rightVal)
+		}
+		// A base of at least 2 bits, b, gives at least (b-1)*exp+1 bits
+		if bits := int64(leftVal.BitLen() - 1); bits > 0 && rightVal.Int64() > (maxIntegerBits-1)/bits {
+			return newError("result too large: %d-bit integer ** %s", leftVal.BitLen(), rightVal)
+		}
+		return object.NewBigInteger(new(big.Int).Exp(leftVal, rightVal, nil))
+	case "&":
+		return object.NewBigInteger(new(big.Int).And(leftVal, rightVal))
+	case "|":
+		return object.NewBigInteger(new(big.Int).Or(leftVal, rightVal))
+	case "^":
+		return object.NewBigInteger(new(big.Int).Xor(leftVal, rightVal))
+	case "<<", ">>":
+		if rightVal.Sign() < 0 {
+			return newError("negative shift amount: %s", rightVal)
+		}
+		if !rightVal.IsInt64() || rightVal.Int64() > math.MaxUint32 {
+			return newError("shift amount too large: %s", rightVal)
+		}
+		if op == "<<" {
+			if leftVal.Sign() != 0 && int64(leftVal.BitLen())+rightVal.Int64() > maxIntegerBits {
+				return newError("result too large: %d-bit integer << %s", leftVal.BitLen(), rightVal)
+			}
+			return object.NewBigInteger(new(big.Int).Lsh(leftVal, uint(rightVal.Int64())))
+		}
+		return object.NewBigInteger(new(big.Int).Rsh(leftVal, uint(rightVal.Int64())))
+	case "<=":
+		return nativeBoolToBooleanObj(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObj(leftVal.Cmp(rightVal) >= 0)
+	case "<":
+		return nativeBoolToBooleanObj(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObj(leftVal.Cmp(rightVal) > 0)
+	case "==":
+		return nativeBoolToBooleanObj(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObj(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", object.INTEGER_OBJ, op, object.INTEGER_OBJ)
+	}
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	if i, ok := obj.(*object.BigInteger); ok {
+		return i.Value
+	}
+	return big.NewInt(obj.(*object.Integer).Value)
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
+
+// powInt64 raises base to a non-negative exponent by repeated squaring.
+func powInt64(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for {
+		if exp&1 == 1 {
+			var ok bool
+			if result, ok = mulInt64(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp == 0 {
+			return result, true
+		}
+		var ok bool
+		if base, ok = mulInt64(base, base); !ok {
+			return 0, false
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	result := forEach(iterable, func(key, value object.Object) object.Object {
+		loopEnv := object.NewBlockEnvironment(env)
+		if node.Key != nil {
+			loopEnv.Set(node.Key.Value, key)
+		}
+		if err := bindTarget(node.Value, value, loopEnv, loopEnv.Set); err != nil {
+			return err
+		}
+
+		result := eval(node.Body, loopEnv)
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			}
+		}
+		return nil
+	})
+	if result != nil {
+		return withPosition(result, node.Token)
+	}
+	return nil
+}
+
+func evalArrayComprehension(node *ast.ArrayComprehension, env *object.Environment) object.Object {
+	elements := []object.Object{}
+	result := comprehend(node.Clause, env, func(loopEnv *object.Environment) object.Object {
+		element := eval(node.Element, loopEnv)
+		if isError(element) {
+			return element
+		}
+		elements = append(elements, element)
+		return nil
+	})
+	if result != nil {
+		return result
+	}
+	return &object.Array{Elements: elements}
+}
+
+func evalMapComprehension(node *ast.MapComprehension, env *object.Environment) object.Object {
+	hash := object.NewHashMap()
+	result := comprehend(node.Clause, env, func(loopEnv *object.Environment) object.Object {
+		key := eval(node.Key, loopEnv)
+		if isError(key) {
+			return key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", typeName(key))
+		}
+		value := eval(node.Value, loopEnv)
+		if isError(value) {
+			return value
+		}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
+		return nil
+	})
+	if result != nil {
+		return result
+	}
+	return hash
+}
+
+// comprehend runs emit once for each element of clause's iterable that
+// passes its condition, in a scope of its own holding the loop
+// variables. It returns the first error, if any.
+func comprehend(clause *ast.ComprehensionClause, env *object.Environment, emit func(*object.Environment) object.Object) object.Object {
+	iterable := eval(clause.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	result := forEach(iterable, func(key, value object.Object) object.Object {
+		loopEnv := object.NewBlockEnvironment(env)
+		if clause.Key != nil {
+			loopEnv.Set(clause.Key.Value, key)
+		}
+		if err := bindTarget(clause.Value, value, loopEnv, loopEnv.Set); err != nil {
+			return err
+		}
+
+		if clause.Condition != nil {
+			condition := eval(clause.Condition, loopEnv)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return nil
+			}
+		}
+		return emit(loopEnv)
+	})
+	if result != nil {
+		return withPosition(result, clause.Token)
+	}
+	return nil
+}
+
+// isIterable reports whether forEach can iterate over obj.
+func isIterable(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Array, *object.String, *object.Range, *object.HashMap, *object.Enum, object.Iterator:
+		return true
+	case *object.Instance:
+		return obj.Operators["iter"] != nil
+	}
+	return false
+}
+
+// forEach calls fn with each key and value in iterable: the index and
+// element of an array, range or iterator, the index and character of a
+// string, or the key and value of a hash map in the order the keys were
+// added. Iteration stops early if fn returns a non-nil result, which
+// forEach then returns, closing the iterator if there is one.
+func forEach(iterable object.Object, fn func(key, value object.Object) object.Object) object.Object {
+	switch it := iterable.(type) {
+	case *object.Array:
+		for i, element := range it.Elements {
+			if result := fn(&object.Integer{Value: int64(i)}, element); result != nil {
+				return result
+			}
+		}
+
+	case *object.String:
+		i := int64(0)
+		for _, char := range it.Value {
+			if result := fn(&object.Integer{Value: i}, &object.String{Value: string(char)}); result != nil {
+				return result
+			}
+			i++
+		}
+
+	case *object.Range:
+		length := it.Len()
+		for i := int64(0); i < length; i++ {
+			if result := fn(&object.Integer{Value: i}, &object.Integer{Value: it.At(i)}); result != nil {
+				return result
+			}
+		}
+
+	case *object.HashMap:
+		for _, pair := range it.Ordered() {
+			if result := fn(pair.Key, pair.Value); result != nil {
+				return result
+			}
+		}
+
+	case *object.Enum:
+		for i, member := range it.Members {
+			if result := fn(&object.Integer{Value: int64(i)}, member); result != nil {
+				return result
+			}
+		}
+
+	case *object.Instance:
+		method := it.Operators["iter"]
+		if method == nil {
+			return newError("cannot iterate over %s", typeName(iterable))
+		}
+		items := applyMethod(method, nil, nil)
+		if isError(items) {
+			return items
+		}
+		return forEach(items, fn)
+
+	case object.Iterator:
+		for i := int64(0); ; i++ {
+			value, ok := it.Next()
+			if !ok {
+				break
+			}
+			if isError(value) {
+				return value
+			}
+			if result := fn(&object.Integer{Value: i}, value); result != nil {
+				it.Close()
+				return result
+			}
+		}
+
+	default:
+		return newError("cannot iterate over %s", typeName(iterable))
+	}
+	return nil
+}
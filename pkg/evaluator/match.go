@@ -0,0 +1,186 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+// typeNames maps the type names usable in patterns to the object types
+// they accept.
+var typeNames = map[string][]object.ObjectType{
+	"int":       {object.INTEGER_OBJ},
+	"float":     {object.FLOAT_OBJ},
+	"string":    {object.STRING_OBJ},
+	"bool":      {object.BOOLEAN_OBJ},
+	"boolean":   {object.BOOLEAN_OBJ},
+	"array":     {object.ARRAY_OBJ},
+	"map":       {object.HASH_OBJ},
+	"range":     {object.RANGE_OBJ},
+	"generator": {object.GENERATOR_OBJ},
+	"meth":      {object.METHOD_OBJ, object.BUILTIN_OBJ},
+	"null":      {object.NULL_OBJ},
+}
+
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewBlockEnvironment(env)
+		matched, err := matchPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return withPosition(err, arm.Token)
+		}
+		if !matched {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		result := eval(arm.Body, armEnv)
+		if result == nil {
+			return NULL
+		}
+		return result
+	}
+	return newError("no match arm for %s", subject.Inspect())
+}
+
+// matchPattern reports whether value matches pattern, binding any names
+// the pattern captures in env.
+func matchPattern(pattern ast.Pattern, value object.Object, env *object.Environment) (bool, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true, nil
+
+	case *ast.BindingPattern:
+		env.Set(pattern.Name.Value, value)
+		return true, nil
+
+	case *ast.TypePattern:
+		ok, err := isType(value, pattern.TypeName.Value, env)
+		if err != nil || !ok {
+			return false, err
+		}
+		if pattern.Name.Value != "_" {
+			env.Set(pattern.Name.Value, value)
+		}
+		return true, nil
+
+	case *ast.ValuePattern:
+		expected := eval(pattern.Value, env)
+		if isError(expected) {
+			return false, expected
+		}
+		if rng, ok := expected.(*object.Range); ok {
+			n, ok := value.(*object.Integer)
+			return ok && rng.Contains(n.Value), nil
+		}
+		return objectsEqual(expected, value), nil
+
+	case *ast.ArrayPattern:
+		return matchArrayPattern(pattern, value, env)
+
+	case *ast.HashPattern:
+		return matchHashPattern(pattern, value, env)
+	}
+	return false, newError("unsupported pattern %s", pattern.String())
+}
+
+func matchArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment) (bool, object.Object) {
+	array, ok := value.(*object.Array)
+	if !ok {
+		return false, nil
+	}
+	if len(array.Elements) < len(pattern.Elements) ||
+		(pattern.Rest == nil && len(array.Elements) != len(pattern.Elements)) {
+		return false, nil
+	}
+
+	for i, element := range pattern.Elements {
+		matched, err := matchPattern(element, array.Elements[i], env)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	if pattern.Rest != nil {
+		rest := make([]object.Object, len(array.Elements)-len(pattern.Elements))
+		copy(rest, array.Elements[len(pattern.Elements):])
+		env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+	}
+	return true, nil
+}
+
+func matchHashPattern(pattern *ast.HashPattern, value object.Object, env *object.Environment) (bool, object.Object) {
+	hash, ok := value.(*object.HashMap)
+	if !ok {
+		return false, nil
+	}
+
+	for i, keyNode := range pattern.Keys {
+		key := eval(keyNode, env)
+		if isError(key) {
+			return false, key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return false, newError("unusable as hash key: %s", typeName(key))
+		}
+		pair, ok := hash.Pairs[hashKey.HashKey()]
+		if !ok {
+			return false, nil
+		}
+		matched, err := matchPattern(pattern.Values[i], pair.Value, env)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// isType reports whether value has the type called name. Besides the
+// builtin types, name can be an object, descriptor or enum defined in env;
+// an instance is described by each of its object's descriptors.
+func isType(value object.Object, name string, env *object.Environment) (bool, object.Object) {
+	types, ok := typeNames[name]
+	if !ok {
+		typ, _ := env.Get(name)
+		inst, isInstance := value.(*object.Instance)
+		switch typ := typ.(type) {
+		case *object.ObjectDefinition:
+			return isInstance && inst.Definition == typ, nil
+		case *object.Descriptor:
+			return isInstance && inst.Definition.Describes(typ), nil
+		case *object.DescriptorView:
+			return isInstance && inst.Definition.Describes(typ.Descriptor), nil
+		case *object.Enum:
+			member, ok := value.(*object.EnumValue)
+			return ok && member.Enum == typ, nil
+		}
+		return false, newError("unknown type: %s", name)
+	}
+	for _, t := range types {
+		if value.Type() == t {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// objectsEqual reports whether a == b would be true, treating values
+// that cannot be compared as unequal.
+func objectsEqual(a, b object.Object) bool {
+	if a.Type() != b.Type() && !(isNumber(a) && isNumber(b)) {
+		return false
+	}
+	return evalInfixExpression("==", a, b) == TRUE
+}
@@ -0,0 +1,576 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+	"sort"
+)
+
+// overloadable lists the names that an overload can use without
+// replacing an inherited method: operators, and builtins that defer to
+// the object they are given.
+var overloadable = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "**": true,
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"[]": true, "len": true, "iter": true,
+}
+
+func init() {
+	// These builtins call back into the evaluator, so they are added
+	// here rather than in the builtins literal to avoid an
+	// initialisation cycle.
+	length := builtins["len"].Method
+	builtins["len"] = &object.BuiltIn{Method: func(args ...object.Object) object.Object {
+		if len(args) == 1 {
+			if inst, ok := args[0].(*object.Instance); ok && inst.Operators["len"] != nil {
+				return applyMethod(inst.Operators["len"], nil, nil)
+			}
+		}
+		return length(args...)
+	}}
+	builtins["sort"] = &object.BuiltIn{Method: sortArray}
+}
+
+func evalDescribeStatement(node *ast.DescribeStatement, env *object.Environment) object.Object {
+	d := &object.Descriptor{
+		Name:      node.Name.Value,
+		Defaults:  make(map[string]object.Object),
+		Constants: make(map[string]object.Object),
+		Methods:   make(map[string]*object.Method),
+		Required:  make(map[string]int),
+		Operators: make(map[string]*object.Method),
+		Types:     make(map[string]string),
+	}
+	declared := make(map[string]bool)
+	declare := func(name *ast.Ident) object.Object {
+		if declared[name.Value] {
+			return withPosition(newError("%s declares %s more than once", d.Name, name.Value), name.Token)
+		}
+		declared[name.Value] = true
+		return nil
+	}
+
+	for i, param := range node.Parameters {
+		if err := declare(param); err != nil {
+			return err
+		}
+		d.Parameters = append(d.Parameters, param.Value)
+		if i < len(node.Types) && node.Types[i] != nil {
+			d.Types[param.Value] = node.Types[i].Value
+		}
+	}
+	for _, prop := range node.Properties {
+		if err := declare(prop.Name); err != nil {
+			return err
+		}
+		value := evalProperty(prop, d.Name, env)
+		if isError(value) {
+			return value
+		}
+		if prop.Type != nil && !prop.Const {
+			d.Types[prop.Name.Value] = prop.Type.Value
+		}
+		if prop.Const {
+			d.Constants[prop.Name.Value] = value
+		} else {
+			d.Properties = append(d.Properties, prop.Name.Value)
+			d.Defaults[prop.Name.Value] = value
+		}
+	}
+	for _, lit := range node.Required {
+		if err := declare(lit.Name); err != nil {
+			return err
+		}
+		d.Required[lit.Name.Value] = len(lit.Parameters)
+	}
+	for _, lit := range node.Methods {
+		if err := declare(lit.Name); err != nil {
+			return err
+		}
+		if lit.Name.Value == "init" {
+			if err := checkInit(lit, d.Name); err != nil {
+				return err
+			}
+			d.Init = newMethod(lit, env)
+			continue
+		}
+		d.Methods[lit.Name.Value] = newMethod(lit, env)
+	}
+	for _, lit := range node.Overloads {
+		if !overloadable[lit.Name.Value] {
+			return withPosition(newError("%s has no method %s to overload", d.Name, lit.Name.Value), lit.Name.Token)
+		}
+		d.Operators[lit.Name.Value] = newMethod(lit, env)
+	}
+	validators, err := newValidators(node.Validators, d.Name, append(d.Parameters, d.Properties...), nil, env)
+	if err != nil {
+		return err
+	}
+	d.Validators = validators
+
+	env.Set(d.Name, d)
+	return nil
+}
+
+// evalObjectStatement merges the members of an object's descriptors, in
+// order, with its own. A property can only come from one place, and a
+// method that two descriptors share must be overloaded by the object.
+func evalObjectStatement(node *ast.ObjectStatement, env *object.Environment) object.Object {
+	def := &object.ObjectDefinition{
+		Name:      node.Name.Value,
+		Defaults:  make(map[string]object.Object),
+		Constants: make(map[string]object.Object),
+		Methods:   make(map[string]*object.Method),
+		Operators: make(map[string]*object.Method),
+		Types:     make(map[string]string),
+		Env:       env,
+	}
+	inherited := make(map[string]*object.Method)
+	owners := make(map[string]string)
+	claim := func(name, owner string) object.Object {
+		if prev, ok := owners[name]; ok {
+			return newError("%s is declared by both %s and %s", name, prev, owner)
+		}
+		owners[name] = owner
+		return nil
+	}
+	// clashes holds the methods and operators that more than one
+	// descriptor provides, which the object has to overload
+	clashes := make(map[string]string)
+
+	for _, ident := range node.Descriptors {
+		value, ok := env.Get(ident.Value)
+		d, isDescriptor := value.(*object.Descriptor)
+		if !ok || !isDescriptor {
+			return withPosition(newError("%s is not a descriptor", ident.Value), ident.Token)
+		}
+		def.Descriptors = append(def.Descriptors, d)
+
+		for _, name := range d.Parameters {
+			if err := claim(name, d.Name); err != nil {
+				return withPosition(err, ident.Token)
+			}
+			def.Parameters = append(def.Parameters, name)
+			def.Properties = append(def.Properties, name)
+		}
+		for _, name := range d.Properties {
+			if err := claim(name, d.Name); err != nil {
+				return withPosition(err, ident.Token)
+			}
+			def.Properties = append(def.Properties, name)
+			def.Defaults[name] = d.Defaults[name]
+		}
+		constants := make([]string, 0, len(d.Constants))
+		for name := range d.Constants {
+			constants = append(constants, name)
+		}
+		sort.Strings(constants)
+		for _, name := range constants {
+			if err := claim(name, d.Name); err != nil {
+				return withPosition(err, ident.Token)
+			}
+			def.Constants[name] = d.Constants[name]
+		}
+		for name, method := range d.Methods {
+			if prev, ok := owners[name]; ok {
+				clashes[name] = prev + " and " + d.Name
+			}
+			owners[name] = d.Name
+			def.Methods[name] = method
+		}
+		for name, method := range d.Operators {
+			if _, ok := def.Operators[name]; ok {
+				clashes[name] = "more than one descriptor"
+			}
+			def.Operators[name] = method
+		}
+		for name, method := range d.Validators {
+			inherited[name] = method
+		}
+		for name, typ := range d.Types {
+			def.Types[name] = typ
+		}
+		if d.Init != nil {
+			def.Inits = append(def.Inits, d.Init)
+		}
+	}
+
+	for _, prop := range node.Properties {
+		if err := claim(prop.Name.Value, def.Name); err != nil {
+			return withPosition(err, prop.Name.Token)
+		}
+		value := evalProperty(prop, def.Name, env)
+		if isError(value) {
+			return value
+		}
+		if prop.Type != nil && !prop.Const {
+			def.Types[prop.Name.Value] = prop.Type.Value
+		}
+		if prop.Const {
+			def.Constants[prop.Name.Value] = value
+		} else {
+			def.Properties = append(def.Properties, prop.Name.Value)
+			def.Defaults[prop.Name.Value] = value
+		}
+	}
+	for _, lit := range node.Methods {
+		if lit.Name.Value == "init" {
+			if err := checkInit(lit, def.Name); err != nil {
+				return err
+			}
+			def.Inits = append(def.Inits, newMethod(lit, env))
+			continue
+		}
+		if owner, ok := owners[lit.Name.Value]; ok {
+			return withPosition(newError("%s already has %s from %s; use overload to replace it",
+				def.Name, lit.Name.Value, owner), lit.Name.Token)
+		}
+		owners[lit.Name.Value] = def.Name
+		def.Methods[lit.Name.Value] = newMethod(lit, env)
+	}
+	for _, lit := range node.Overloads {
+		name := lit.Name.Value
+		switch {
+		case overloadable[name]:
+			def.Operators[name] = newMethod(lit, env)
+		case def.Methods[name] != nil:
+			def.Methods[name] = newMethod(lit, env)
+		default:
+			return withPosition(newError("%s has no method %s to overload", def.Name, name), lit.Name.Token)
+		}
+		delete(clashes, name)
+	}
+	if len(clashes) > 0 {
+		names := make([]string, 0, len(clashes))
+		for name := range clashes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return withPosition(newError("%s gets %s from %s; overload it to choose one",
+			def.Name, names[0], clashes[names[0]]), node.Name.Token)
+	}
+	if err := checkRequired(def); err != nil {
+		return withPosition(err, node.Name.Token)
+	}
+	validators, err := newValidators(node.Validators, def.Name, def.Properties, inherited, env)
+	if err != nil {
+		return err
+	}
+	def.Validators = validators
+
+	env.Set(def.Name, def)
+	return nil
+}
+
+// checkRequired reports an error if def doesn't provide a method that
+// one of its descriptors requires, or provides one that can't be called
+// with the required number of arguments. The method can come from the
+// object or from another of its descriptors.
+func checkRequired(def *object.ObjectDefinition) object.Object {
+	for _, d := range def.Descriptors {
+		names := make([]string, 0, len(d.Required))
+		for name := range d.Required {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			method, ok := def.Methods[name]
+			if !ok {
+				return newError("%s must provide method %s required by %s", def.Name, name, d.Name)
+			}
+			want := d.Required[name]
+			if required := requiredParameters(method); want < required || want > len(method.Parameters) {
+				got := fmt.Sprint(len(method.Parameters))
+				if required != len(method.Parameters) {
+					got = fmt.Sprintf("%d to %d", required, len(method.Parameters))
+				}
+				return newError("%s.%s must take %d parameters to satisfy %s, got %s",
+					def.Name, name, want, d.Name, got)
+			}
+		}
+	}
+	return nil
+}
+
+// newInstance builds an instance of def, binding args to its
+// parameters. Properties start with their declared values, and methods
+// are bound to run in the instance's scope.
+func newInstance(def *object.ObjectDefinition, args []object.Object, names []string) object.Object {
+	bound := make([]object.Object, len(def.Parameters))
+	named := false
+	for i, arg := range args {
+		idx := i
+		if i < len(names) && names[i] != "" {
+			named = true
+			idx = -1
+			for j, param := range def.Parameters {
+				if param == names[i] {
+					idx = j
+				}
+			}
+			if idx < 0 {
+				return newError("%s has no parameter %s", def.Name, names[i])
+			}
+			if bound[idx] != nil {
+				return newError("argument %s given more than once", names[i])
+			}
+		} else if i >= len(def.Parameters) {
+			return newError("wrong number of arguments to %s: want %d, got %d", def.Name, len(def.Parameters), len(args))
+		}
+		bound[idx] = arg
+	}
+	for i, value := range bound {
+		if value == nil && !named {
+			return newError("wrong number of arguments to %s: want %d, got %d", def.Name, len(def.Parameters), len(args))
+		}
+		if value == nil {
+			return newError("missing argument for %s.%s", def.Name, def.Parameters[i])
+		}
+	}
+
+	env := object.NewEnclosedEnvironment(def.Env)
+	inst := &object.Instance{Definition: def, Env: env, Operators: make(map[string]*object.Method)}
+	env.SetOwner(inst)
+	for name, value := range def.Constants {
+		env.Set(name, value)
+	}
+	for name, value := range def.Defaults {
+		env.Set(name, value)
+	}
+	for i, name := range def.Parameters {
+		env.Set(name, bound[i])
+	}
+	for name, method := range def.Methods {
+		env.Set(name, bindMethod(method, inst))
+	}
+	for name, method := range def.Operators {
+		inst.Operators[name] = bindMethod(method, inst)
+	}
+	for _, d := range def.Descriptors {
+		env.Set(d.Name, &object.DescriptorView{Descriptor: d, Instance: inst})
+	}
+
+	for _, init := range def.Inits {
+		if result := applyMethod(bindMethod(init, inst), nil, nil); isError(result) {
+			return result
+		}
+	}
+	for _, name := range def.Properties {
+		value, _ := env.GetLocal(name)
+		if err := validate(inst, name, value); err != nil {
+			return err
+		}
+	}
+	return inst
+}
+
+// checkInit reports an error if an init method takes parameters, since
+// it is called with none.
+func checkInit(lit *ast.FuncLiteral, owner string) object.Object {
+	if len(lit.Parameters) != 0 {
+		return withPosition(newError("init in %s takes no parameters, got %d", owner, len(lit.Parameters)), lit.Name.Token)
+	}
+	return nil
+}
+
+// newValidators builds the validators declared by owner, which must
+// each name one of props, adding them to those it inherits.
+func newValidators(lits []*ast.FuncLiteral, owner string, props []string, inherited map[string]*object.Method, env *object.Environment) (map[string]*object.Method, object.Object) {
+	validators := make(map[string]*object.Method)
+	for name, method := range inherited {
+		validators[name] = method
+	}
+	for _, lit := range lits {
+		name := lit.Name.Value
+		if !contains(props, name) {
+			return nil, withPosition(newError("%s has no property %s to validate", owner, name), lit.Name.Token)
+		}
+		if validators[name] != nil {
+			return nil, withPosition(newError("%s is validated more than once", name), lit.Name.Token)
+		}
+		validators[name] = newMethod(lit, env)
+	}
+	return validators, nil
+}
+
+// evalProperty evaluates the value a property of owner is declared
+// with, checking it against the property's type annotation.
+func evalProperty(prop *ast.Property, owner string, env *object.Environment) object.Object {
+	value := eval(prop.Value, env)
+	if isError(value) || prop.Type == nil {
+		return value
+	}
+	if err := checkType(value, prop.Type.Value, env, "property %s of %s", prop.Name.Value, owner); err != nil {
+		return withPosition(err, prop.Name.Token)
+	}
+	return value
+}
+
+// validate checks value against the type annotation of inst's property
+// name, then runs its validator, if it has one. The validator can
+// reject value by returning false or by raising an error.
+func validate(inst *object.Instance, name string, value object.Object) object.Object {
+	def := inst.Definition
+	if typ, ok := def.Types[name]; ok {
+		if err := checkType(value, typ, def.Env, "property %s of %s", name, def.Name); err != nil {
+			return err
+		}
+	}
+	validator := inst.Definition.Validators[name]
+	if validator == nil {
+		return nil
+	}
+	result := applyMethod(bindMethod(validator, inst), []object.Object{value}, nil)
+	if isError(result) {
+		return result
+	}
+	if !isTruthy(result) {
+		return newError("invalid %s for %s: %s", name, inst.Definition.Name, value.Inspect())
+	}
+	return nil
+}
+
+// setProperty assigns value to the property name of an instance, or of
+// the instance a descriptor view belongs to, once it passes validation.
+func setProperty(target object.Object, name string, value object.Object) object.Object {
+	var inst *object.Instance
+	switch target := target.(type) {
+	case *object.Instance:
+		inst = target
+	case *object.DescriptorView:
+		d := target.Descriptor
+		if _, ok := d.Defaults[name]; !ok && !contains(d.Parameters, name) {
+			if _, ok := d.Constants[name]; ok {
+				return newError("cannot assign to constant %s", name)
+			}
+			return newError("%s does not describe property %s", d.Name, name)
+		}
+		inst = target.Instance
+	default:
+		return newError("cannot set %s of %s", name, typeName(target))
+	}
+
+	if _, ok := inst.Definition.Constants[name]; ok {
+		return newError("cannot assign to constant %s", name)
+	}
+	if !contains(inst.Definition.Properties, name) {
+		return newError("%s has no property %s", inst.Definition.Name, name)
+	}
+	if err := validate(inst, name, value); err != nil {
+		return err
+	}
+	inst.Env.Set(name, value)
+	return nil
+}
+
+// assign sets name to value in env. Assigning to a property or constant
+// from a method of the instance it belongs to goes through setProperty,
+// unless the method has a variable of its own called name.
+func assign(env *object.Environment, name string, value object.Object) object.Object {
+	if owner, ok := env.Owner(name); ok {
+		inst := owner.(*object.Instance)
+		_, isConst := inst.Definition.Constants[name]
+		if isConst || contains(inst.Definition.Properties, name) {
+			return setProperty(inst, name, value)
+		}
+	}
+	env.Assign(name, value)
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bindMethod returns a copy of method that runs in inst. A method that
+// comes from a descriptor sees the instance's members and then the scope
+// the descriptor was declared in, rather than the object's.
+func bindMethod(method *object.Method, inst *object.Instance) *object.Method {
+	bound := *method
+	bound.Env = inst.Env
+	if method.Env != inst.Definition.Env {
+		bound.Env = inst.Env.WithOuter(method.Env)
+	}
+	return &bound
+}
+
+// evalOperatorOverload applies an operator that an instance overloads.
+// Only the left operand's overload is used, except that != falls back
+// to negating ==, == is tried either way round, and >, <= and >= are
+// worked out from < when it is overloaded. It reports false if no
+// overload applies.
+func evalOperatorOverload(op string, left, right object.Object) (object.Object, bool) {
+	l, _ := left.(*object.Instance)
+	r, _ := right.(*object.Instance)
+	if l == nil && r == nil {
+		return nil, false
+	}
+	call := func(inst *object.Instance, op string, arg object.Object) object.Object {
+		return applyMethod(inst.Operators[op], []object.Object{arg}, nil)
+	}
+	not := func(result object.Object) object.Object {
+		if isError(result) {
+			return result
+		}
+		return nativeBoolToBooleanObj(!isTruthy(result))
+	}
+
+	switch {
+	case l != nil && l.Operators[op] != nil:
+		return call(l, op, right), true
+	case op == "==" && r != nil && r.Operators["=="] != nil:
+		return call(r, "==", left), true
+	case op == "!=":
+		if result, ok := evalOperatorOverload("==", left, right); ok {
+			return not(result), true
+		}
+	case op == ">" && r != nil && r.Operators["<"] != nil:
+		return call(r, "<", left), true
+	case op == "<=" && r != nil && r.Operators["<"] != nil:
+		return not(call(r, "<", left)), true
+	case op == ">=" && l != nil && l.Operators["<"] != nil:
+		return not(call(l, "<", right)), true
+	}
+	return nil, false
+}
+
+// sortArray returns a sorted copy of an array, ordered by <, so it can
+// sort numbers, strings, or objects that overload <.
+func sortArray(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments, want 1, got %d", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `sort` must be ARRAY, got %s", typeName(args[0]))
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+	var err object.Object
+	sort.SliceStable(elements, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		result := evalInfixExpression("<", elements[i], elements[j])
+		if isError(result) {
+			err = result
+			return false
+		}
+		if result.Type() != object.BOOLEAN_OBJ {
+			err = newError("< must give a BOOLEAN to sort, got %s", typeName(result))
+			return false
+		}
+		return result == TRUE
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
@@ -0,0 +1,59 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+// maxArrayLen caps how many elements array() will materialise from a
+// range, so a mistyped bound fails cleanly instead of exhausting memory.
+const maxArrayLen = 1 << 26
+
+func evalRangeExpression(node *ast.RangeExpression, env *object.Environment) object.Object {
+	bounds := []ast.Expr{node.Start, node.End}
+	if node.Step != nil {
+		bounds = append(bounds, node.Step)
+	}
+
+	values := make([]int64, 0, 3)
+	for _, bound := range bounds {
+		obj := eval(bound, env)
+		if isError(obj) {
+			return obj
+		}
+		if big, ok := obj.(*object.BigInteger); ok {
+			return newError("range bound %s does not fit in 64 bits", big.Value)
+		}
+		integer, ok := obj.(*object.Integer)
+		if !ok {
+			return newError("range bounds must be 64-bit INTEGER, got %s", typeName(obj))
+		}
+		values = append(values, integer.Value)
+	}
+
+	step := int64(1)
+	if len(values) == 3 {
+		step = values[2]
+		if step == 0 {
+			return newError("range step cannot be zero")
+		}
+	}
+	return &object.Range{Start: values[0], End: values[1], Step: step, Inclusive: node.Inclusive}
+}
+
+func evalRangeIndexExpression(rng, index object.Object) object.Object {
+	r := rng.(*object.Range)
+	integer, ok := index.(*object.Integer)
+	if !ok {
+		return NULL
+	}
+	idx := integer.Value
+	length := r.Len()
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return NULL
+	}
+	return &object.Integer{Value: r.At(idx)}
+}
@@ -0,0 +1,72 @@
+package evaluator
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+// evalTryExpression evaluates the body of a try, and if it fails
+// evaluates the catch block instead, with the error bound as a map.
+func evalTryExpression(node *ast.TryExpression, env *object.Environment) object.Object {
+	result := evalTryBody(node, env)
+	err, ok := result.(*object.Error)
+	if !ok {
+		return result
+	}
+
+	catchEnv := object.NewBlockEnvironment(env)
+	if node.Name != nil {
+		catchEnv.Set(node.Name.Value, errorValue(err))
+	}
+	return eval(node.Catch, catchEnv)
+}
+
+// evalTryBody evaluates the body of a try, turning a fault in the
+// interpreter into an error at the try so that the catch can handle it
+// like any other. A generator being closed is not a fault, so it is let
+// through.
+func evalTryBody(node *ast.TryExpression, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			if object.IsStopGenerator(r) {
+				panic(r)
+			}
+			result = withPosition(newError("internal error: %v", r), node.Token)
+		}
+	}()
+	return eval(node.Body, env)
+}
+
+// evalErrorExpression raises an error whose message is the string
+// given, or the printed form of any other value.
+func evalErrorExpression(node *ast.ErrorExpression, env *object.Environment) object.Object {
+	msg := eval(node.Message, env)
+	if isError(msg) {
+		return msg
+	}
+	text := msg.Inspect()
+	if str, ok := msg.(*object.String); ok {
+		text = str.Value
+	}
+	return withPosition(newError("%s", text), node.Token)
+}
+
+// errorValue describes err as a map of its message, position and
+// stack, so that a catch block can inspect it without raising it again.
+func errorValue(err *object.Error) *object.HashMap {
+	stack := make([]object.Object, len(err.Stack))
+	for i, frame := range err.Stack {
+		stack[i] = &object.String{Value: frame}
+	}
+
+	hash := object.NewHashMap()
+	set := func(key string, value object.Object) {
+		k := &object.String{Value: key}
+		hash.Set(k.HashKey(), object.HashPair{Key: k, Value: value})
+	}
+	set("message", &object.String{Value: err.Message})
+	set("line", &object.Integer{Value: int64(err.Line)})
+	set("col", &object.Integer{Value: int64(err.Col)})
+	set("stack", &object.Array{Elements: stack})
+	return hash
+}
@@ -0,0 +1,56 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/alexjwhite-cb/jet/pkg/object"
+)
+
+// checkType reports an error if value doesn't have the type called
+// name, which is resolved in env. The error starts with what, formatted
+// with a, to say which value was wrong.
+func checkType(value object.Object, name string, env *object.Environment, what string, a ...interface{}) object.Object {
+	if value == nil {
+		value = NULL
+	}
+	ok, err := isType(value, name, env)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newError("%s: want %s, got %s", fmt.Sprintf(what, a...), name, typeName(value))
+	}
+	return nil
+}
+
+// checkReturnType passes result through, unless fn is annotated with a
+// return type that result doesn't have.
+func checkReturnType(fn *object.Method, result object.Object) object.Object {
+	if fn.ReturnType == nil || isError(result) {
+		return result
+	}
+	if err := checkType(result, fn.ReturnType.Value, fn.Env, "return value of %s", methodName(fn)); err != nil {
+		return err
+	}
+	return result
+}
+
+// typeName is how errors refer to the type of obj: an instance by its
+// object's name, an enum member by its enum's, and anything else by its
+// type.
+func typeName(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.Instance:
+		return obj.Definition.Name
+	case *object.EnumValue:
+		return obj.Enum.Name
+	}
+	return string(obj.Type())
+}
+
+// methodName is how errors refer to fn.
+func methodName(fn *object.Method) string {
+	if fn.Name == "" {
+		return "method"
+	}
+	return fn.Name
+}
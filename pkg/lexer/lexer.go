@@ -1,8 +1,11 @@
 package lexer
 
 import (
+	"fmt"
 	"github.com/alexjwhite-cb/jet/pkg/token"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Lexer is a stateful struct that evolves as
@@ -16,11 +19,19 @@ type Lexer struct {
 	wordStart    int
 	column       int
 	char         rune
+	// width is the number of bytes char was decoded from
+	width int
+	// invalid is set when char is a byte that is not valid UTF-8
+	invalid    bool
+	lineStarts []int
+	errors     []string
+	// failed is set once an error has been recorded for the current token
+	failed bool
 }
 
 // New instantiates a new Lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input, line: 1, column: 0}
+	l := &Lexer{input: input, line: 1, column: 0, lineStarts: []int{0}}
 	l.readChar()
 	return l
 }
@@ -53,6 +64,7 @@ func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	l.failed = false
 
 	switch l.char {
 	case '{':
@@ -70,37 +82,64 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.char, l.column, l.line)
 	case '.':
-		tok = newToken(token.STOP, l.char, l.column, l.line)
+		if l.peekChar() == '.' {
+			tok = token.Token{Type: token.RANGE, Literal: "..", Col: l.column, Line: l.line}
+			l.readChar()
+			switch l.peekChar() {
+			case '<':
+				l.readChar()
+				tok.Type, tok.Literal = token.RANGEEXCL, "..<"
+			case '.':
+				l.readChar()
+				tok.Type, tok.Literal = token.ELLIPSIS, "..."
+			}
+		} else {
+			tok = newToken(token.STOP, l.char, l.column, l.line)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.char, l.column, l.line)
 	case ':':
 		tok = newToken(token.COLON, l.char, l.column, l.line)
 	case '?':
-		tok = newToken(token.QUESTION, l.char, l.column, l.line)
-	case '*':
-		tok = newToken(token.MULTIPLY, l.char, l.column, l.line)
+		switch l.peekChar() {
+		case '.':
+			tok = token.Token{Type: token.OPTDOT, Literal: "?.", Col: l.column, Line: l.line}
+			l.readChar()
+		case '[':
+			tok = token.Token{Type: token.OPTINDEX, Literal: "?[", Col: l.column, Line: l.line}
+			l.readChar()
+		case '?':
+			tok = token.Token{Type: token.COALESCE, Literal: "??", Col: l.column, Line: l.line}
+			l.readChar()
+		default:
+			tok = newToken(token.QUESTION, l.char, l.column, l.line)
+		}
 	case '/':
 		tok = newToken(token.DIVIDE, l.char, l.column, l.line)
+	case '%':
+		tok = newToken(token.MODULO, l.char, l.column, l.line)
+	case '^':
+		tok = newToken(token.BITXOR, l.char, l.column, l.line)
+	case '~':
+		tok = newToken(token.BITNOT, l.char, l.column, l.line)
 	case '\n', '\r':
 		tok = newToken(token.NEWLINE, l.char, l.column, l.line)
-		l.line++
-		l.column = 0
+		l.newLine()
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
 	default:
 
 		switch {
-		case unicode.IsLetter(l.char):
+		case isLetter(l.char):
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
 			tok.Col = l.wordStart
 			tok.Line = l.line
 			return tok
 
-		case unicode.IsNumber(l.char):
-			tok.Literal = l.readInt()
-			tok.Type = token.INT
+		case isDecimal(l.char):
+			tok.Literal, tok.Type = l.readNumber()
 			tok.Col = l.wordStart
 			tok.Line = l.line
 			return tok
@@ -113,13 +152,25 @@ func (l *Lexer) NextToken() token.Token {
 			return tok
 
 		case l.char == '"':
-			tok.Literal = l.readString()
-			tok.Type = token.STRING
+			tok.Literal, tok.Type = l.readString()
 			tok.Col = l.wordStart
 			tok.Line = l.line
 			return tok
 
+		case l.char == '`':
+			tok.Line, tok.Col = l.line, l.column
+			tok.Literal, tok.Type = l.readRawString()
+			return tok
+
+		case l.invalid:
+			l.errorAt(l.column, "invalid UTF-8 encoding")
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.input[l.position:l.readPosition]
+			tok.Col = l.column
+			tok.Line = l.line
+
 		default:
+			l.errorAt(l.column, "illegal character %q", l.char)
 			tok = newToken(token.ILLEGAL, l.char, l.column, l.line)
 		}
 
@@ -130,62 +181,335 @@ func (l *Lexer) NextToken() token.Token {
 
 }
 
-// readChar updates the current character being inspected
-// and allows look-ahead.
+// readChar decodes the next UTF-8 character and advances past it.
+// Columns count characters rather than bytes; position and
+// readPosition remain byte offsets into input.
 func (l *Lexer) readChar() {
+	l.invalid = false
 	if l.readPosition >= len(l.input) {
 		l.char = 0
+		l.width = 0
 	} else {
-		l.char = rune(l.input[l.readPosition])
+		l.char, l.width = utf8.DecodeRuneInString(l.input[l.readPosition:])
+		l.invalid = l.char == utf8.RuneError && l.width == 1
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += l.width
 	l.column += 1
 }
 
+// newLine moves the column count onto the next line.
+func (l *Lexer) newLine() {
+	l.line++
+	l.column = 0
+	l.lineStarts = append(l.lineStarts, l.readPosition)
+}
+
+// Offset converts a token's line and column, which count characters,
+// into a byte offset into the input. It returns -1 for a position the
+// lexer has not reached.
+func (l *Lexer) Offset(line, col int) int {
+	if line < 1 || line > len(l.lineStarts) || col < 1 {
+		return -1
+	}
+	offset := l.lineStarts[line-1]
+	for i := 1; i < col; i++ {
+		if offset >= len(l.input) {
+			return -1
+		}
+		_, width := utf8.DecodeRuneInString(l.input[offset:])
+		offset += width
+	}
+	return offset
+}
+
 // readIdentifier concisely reads variables, function names, and keywords
 func (l *Lexer) readIdentifier() string {
 	l.start = l.position
 	l.wordStart = l.column
-	for unicode.IsLetter(l.char) || unicode.IsDigit(l.char) || l.char == '_' {
+	for isLetter(l.char) || unicode.IsDigit(l.char) {
 		l.readChar()
 	}
 	return l.input[l.start:l.position]
 }
 
-// readInt concisely reads integer values
-func (l *Lexer) readInt() string {
+// Errors returns the malformed tokens found so far, with
+// the line and column at which each problem starts.
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+
+func (l *Lexer) errorAt(col int, format string, a ...interface{}) {
+	l.errorAtPos(l.line, col, format, a...)
+}
+
+func (l *Lexer) errorAtPos(line, col int, format string, a ...interface{}) {
+	if l.failed {
+		return
+	}
+	l.failed = true
+	msg := fmt.Sprintf("line %v, col %v: %s", line, col, fmt.Sprintf(format, a...))
+	l.errors = append(l.errors, msg)
+}
+
+// peekChar looks at the next character without consuming it
+func (l *Lexer) peekChar() rune {
+	if l.readPosition >= len(l.input) {
+		return 0
+	}
+	char, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return char
+}
+
+// readNumber reads decimal, hex (0x), octal (0o) and binary (0b) integers
+// and decimal floats such as 1.5e-3. Digits may be separated by single
+// underscores. A malformed number is returned whole as an ILLEGAL token,
+// with an error recorded against its first bad character.
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	l.start = l.position
 	l.wordStart = l.column
-	for unicode.IsNumber(l.char) {
+	tokType := token.TokenType(token.INT)
+
+	base := 10
+	if l.char == '0' {
+		switch l.peekChar() {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+	}
+
+	if base != 10 {
+		prefix := l.input[l.position : l.position+2]
+		l.readChar()
+		l.readChar()
+		if !l.readDigits(base, true) {
+			l.errorAt(l.column, "%s has no digits after %s prefix", baseName(base), prefix)
+		}
+	} else {
+		l.readDigits(10, false)
+		if l.char == '.' && isDecimal(l.peekChar()) {
+			tokType = token.FLOAT
+			l.readChar()
+			l.readDigits(10, false)
+		}
+		if l.char == 'e' || l.char == 'E' {
+			tokType = token.FLOAT
+			l.readChar()
+			if l.char == '+' || l.char == '-' {
+				l.readChar()
+			}
+			if !l.readDigits(10, false) {
+				l.errorAt(l.column, "float exponent has no digits")
+			}
+		}
+		if tokType == token.INT && l.input[l.start] == '0' && l.position-l.start > 1 {
+			l.errorAt(l.wordStart, "leading zero in decimal literal; use 0o for octal")
+		}
+	}
+
+	// Letters, digits and underscores running on from a number would
+	// otherwise be read as a separate identifier.
+	for isLetter(l.char) || unicode.IsDigit(l.char) {
+		l.errorAt(l.column, "invalid character %q in %s", l.char, baseName(base))
 		l.readChar()
 	}
-	return l.input[l.start:l.position]
+
+	literal := l.input[l.start:l.position]
+	if l.failed {
+		return literal, token.ILLEGAL
+	}
+	return literal, tokType
 }
 
-// readInt concisely reads integer values
-func (l *Lexer) readString() string {
-	l.start = l.position + 1
+// readDigits consumes digits in base along with the underscores between
+// them, and reports whether any digits were read. afterPrefix permits an
+// underscore straight after a base prefix, as in 0x_FF.
+func (l *Lexer) readDigits(base int, afterPrefix bool) bool {
+	digits := false
+	prevUnderscore := false
+	underscoreCol := 0
+	for {
+		switch {
+		case l.char == '_':
+			if prevUnderscore || (!digits && !afterPrefix) {
+				l.errorAt(l.column, "'_' must separate digits")
+			}
+			prevUnderscore = true
+			underscoreCol = l.column
+		case isHex(l.char) && (base == 16 || isDecimal(l.char)):
+			if digitValue(l.char) >= base {
+				l.errorAt(l.column, "invalid digit %q in %s", l.char, baseName(base))
+			}
+			digits = true
+			prevUnderscore = false
+		default:
+			if prevUnderscore {
+				l.errorAt(underscoreCol, "'_' must separate digits")
+			}
+			return digits
+		}
+		l.readChar()
+	}
+}
+
+// isLetter reports whether char may start an identifier: any Unicode
+// letter, or an underscore.
+func isLetter(char rune) bool {
+	return unicode.IsLetter(char) || char == '_'
+}
+
+func isDecimal(char rune) bool {
+	return '0' <= char && char <= '9'
+}
+
+func isHex(char rune) bool {
+	return isDecimal(char) || ('a' <= char && char <= 'f') || ('A' <= char && char <= 'F')
+}
+
+func digitValue(char rune) int {
+	switch {
+	case isDecimal(char):
+		return int(char - '0')
+	case 'a' <= char && char <= 'f':
+		return int(char-'a') + 10
+	default:
+		return int(char-'A') + 10
+	}
+}
+
+func baseName(base int) string {
+	switch base {
+	case 2:
+		return "binary literal"
+	case 8:
+		return "octal literal"
+	case 16:
+		return "hexadecimal literal"
+	default:
+		return "decimal literal"
+	}
+}
+
+// readString reads a double-quoted string, decoding the escape sequences
+// \n, \t, \r, \0, \\, \" and \u{hex}. A string must close on the line it
+// opens on; otherwise the token is ILLEGAL and the error points at the
+// opening quote.
+func (l *Lexer) readString() (string, token.TokenType) {
 	l.wordStart = l.column
+	var out strings.Builder
 	for {
 		l.readChar()
-		if l.char == '\\' && l.input[l.readPosition] == '"' {
+		switch l.char {
+		case '"':
+			l.readChar()
+			if l.failed {
+				return out.String(), token.ILLEGAL
+			}
+			return out.String(), token.STRING
+		case 0, '\n', '\r':
+			l.errorAt(l.wordStart, "unterminated string literal")
+			return out.String(), token.ILLEGAL
+		case '\\':
+			l.readEscape(&out)
+		default:
+			if l.invalid {
+				l.errorAt(l.column, "invalid UTF-8 encoding in string literal")
+			}
+			out.WriteString(l.input[l.position:l.readPosition])
+		}
+	}
+}
+
+// readEscape decodes the escape sequence after a backslash into out.
+func (l *Lexer) readEscape(out *strings.Builder) {
+	escapeCol := l.column
+	switch l.peekChar() {
+	case 0, '\n', '\r':
+		// Leave the line ending for readString to report.
+		return
+	}
+	l.readChar()
+	switch l.char {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '0':
+		out.WriteByte(0)
+	case '\\', '"':
+		out.WriteByte(byte(l.char))
+	case 'u':
+		if l.peekChar() != '{' {
+			l.errorAt(escapeCol, "\\u escape must be written as \\u{hex}")
+			return
+		}
+		l.readChar()
+		value, digits := 0, 0
+		for isHex(l.peekChar()) {
 			l.readChar()
-			continue
+			value = value*16 + digitValue(l.char)
+			digits++
+			if digits > 6 {
+				break
+			}
+		}
+		if l.peekChar() != '}' || digits == 0 || digits > 6 {
+			l.errorAt(escapeCol, "\\u escape must be written as \\u{hex}")
+			return
+		}
+		l.readChar()
+		if value > unicode.MaxRune || (0xD800 <= value && value <= 0xDFFF) {
+			l.errorAt(escapeCol, "invalid code point U+%X in \\u escape", value)
+			return
 		}
-		if l.char == '"' || l.char == 0 {
+		out.WriteRune(rune(value))
+	default:
+		l.errorAt(escapeCol, "unknown escape sequence \\%c", l.char)
+	}
+}
+
+// readRawString reads a backtick-quoted string. Nothing inside is
+// escaped, newlines are kept and carriage returns dropped, so raw
+// strings suit templates and embedded JSON.
+func (l *Lexer) readRawString() (string, token.TokenType) {
+	startLine, startCol := l.line, l.column
+	var out strings.Builder
+	for {
+		l.readChar()
+		switch l.char {
+		case '`':
 			l.readChar()
-			break
+			if l.failed {
+				return out.String(), token.ILLEGAL
+			}
+			return out.String(), token.STRING
+		case 0:
+			l.errorAtPos(startLine, startCol, "unterminated raw string literal")
+			return out.String(), token.ILLEGAL
+		case '\r':
+		case '\n':
+			out.WriteByte('\n')
+			l.newLine()
+		default:
+			if l.invalid {
+				l.errorAt(l.column, "invalid UTF-8 encoding in string literal")
+			}
+			out.WriteString(l.input[l.position:l.readPosition])
 		}
 	}
-	return l.input[l.start : l.position-1]
 }
 
 // IsOperator checks to see if the current string value
 // is equivalent to anything in the single-character operator list.
 func (l *Lexer) isOperator() bool {
 	switch l.char {
-	case '+', '-', '=', '<', '>', '|', '&', '!':
+	case '+', '-', '*', '=', '<', '>', '|', '&', '!':
 		return true
 	}
 	return false
@@ -208,19 +532,23 @@ func (l *Lexer) readOperator() string {
 		case '-', '=', '>':
 			l.readChar()
 		}
-	case '!', '=', '<', '>':
+	case '<', '>':
+		first := l.char
 		l.readChar()
-		if l.char == '=' {
+		if l.char == '=' || l.char == first {
 			l.readChar()
 		}
-	case '&':
+	case '!', '=':
 		l.readChar()
-		if l.char == '&' {
+		if l.char == '=' {
 			l.readChar()
 		}
-	case '|':
+	case '*', '&', '|':
+		// Each of these is an operator on its own, or doubled as
+		// in **, && and ||.
+		first := l.char
 		l.readChar()
-		if l.char == '|' {
+		if l.char == first {
 			l.readChar()
 		}
 	case 0:
@@ -46,7 +46,10 @@ const (
 	str = "\"Hello, World!\""
 	(str)->
 }`
-	andOperator = `meth main {
+	rawString      = "x = `{\n\t\"a\": 1\n}`\ny = 2"
+	unicodeText    = "名前 = \"héllo, 世界\" + _x\nçà = 1"
+	rangeOperators = "0..10 step 2\n1..<n.x\nf(...a)"
+	andOperator    = `meth main {
 	if a == 2 * 2 && !b {
 		(true)->
 	} else {
@@ -59,6 +62,8 @@ const (
 	}
 	(false)->
 }`
+	arithmeticOperators = `x = a % b ** 2
+y = ~a & b | c ^ d << 1 >> 2`
 	func1 = `meth NewGuitar: tuning {
 	guitar = Guitar->new
 	tuning = tuning->toUpper
@@ -252,7 +257,7 @@ func TestLexer_NextToken(t *testing.T) {
 				{token.NEWLINE, "\n", 12, 1},
 				{token.IDENT, "str", 2, 2},
 				{token.ASSIGN, "=", 6, 2},
-				{token.STRING, "\\\"Hello, World!\\\"", 8, 2},
+				{token.STRING, "\"Hello, World!\"", 8, 2},
 				{token.NEWLINE, "\n", 27, 2},
 				{token.LPAREN, "(", 2, 3},
 				{token.IDENT, "str", 3, 3},
@@ -262,6 +267,57 @@ func TestLexer_NextToken(t *testing.T) {
 				{token.RBRACE, "}", 1, 4},
 			},
 		},
+		{
+			name: "Raw String",
+			in:   rawString,
+			expect: []token.Token{
+				{token.IDENT, "x", 1, 1},
+				{token.ASSIGN, "=", 3, 1},
+				{token.STRING, "{\n\t\"a\": 1\n}", 5, 1},
+				{token.NEWLINE, "\n", 3, 3},
+				{token.IDENT, "y", 1, 4},
+				{token.ASSIGN, "=", 3, 4},
+				{token.INT, "2", 5, 4},
+			},
+		},
+		{
+			name: "Unicode",
+			in:   unicodeText,
+			expect: []token.Token{
+				{token.IDENT, "名前", 1, 1},
+				{token.ASSIGN, "=", 4, 1},
+				{token.STRING, "héllo, 世界", 6, 1},
+				{token.PLUS, "+", 18, 1},
+				{token.IDENT, "_x", 20, 1},
+				{token.NEWLINE, "\n", 22, 1},
+				{token.IDENT, "çà", 1, 2},
+				{token.ASSIGN, "=", 4, 2},
+				{token.INT, "1", 6, 2},
+			},
+		},
+		{
+			name: "Range Operators",
+			in:   rangeOperators,
+			expect: []token.Token{
+				{token.INT, "0", 1, 1},
+				{token.RANGE, "..", 2, 1},
+				{token.INT, "10", 4, 1},
+				{token.IDENT, "step", 7, 1},
+				{token.INT, "2", 12, 1},
+				{token.NEWLINE, "\n", 13, 1},
+				{token.INT, "1", 1, 2},
+				{token.RANGEEXCL, "..<", 2, 2},
+				{token.IDENT, "n", 5, 2},
+				{token.STOP, ".", 6, 2},
+				{token.IDENT, "x", 7, 2},
+				{token.NEWLINE, "\n", 8, 2},
+				{token.IDENT, "f", 1, 3},
+				{token.LPAREN, "(", 2, 3},
+				{token.ELLIPSIS, "...", 3, 3},
+				{token.IDENT, "a", 6, 3},
+				{token.RPAREN, ")", 7, 3},
+			},
+		},
 		{
 			name: "And Operator",
 			in:   andOperator,
@@ -334,6 +390,34 @@ func TestLexer_NextToken(t *testing.T) {
 				{token.RBRACE, "}", 1, 6},
 			},
 		},
+		{
+			name: "Arithmetic Operators",
+			in:   arithmeticOperators,
+			expect: []token.Token{
+				{token.IDENT, "x", 1, 1},
+				{token.ASSIGN, "=", 3, 1},
+				{token.IDENT, "a", 5, 1},
+				{token.MODULO, "%", 7, 1},
+				{token.IDENT, "b", 9, 1},
+				{token.POWER, "**", 11, 1},
+				{token.INT, "2", 14, 1},
+				{token.NEWLINE, "\n", 15, 1},
+				{token.IDENT, "y", 1, 2},
+				{token.ASSIGN, "=", 3, 2},
+				{token.BITNOT, "~", 5, 2},
+				{token.IDENT, "a", 6, 2},
+				{token.BITAND, "&", 8, 2},
+				{token.IDENT, "b", 10, 2},
+				{token.BITOR, "|", 12, 2},
+				{token.IDENT, "c", 14, 2},
+				{token.BITXOR, "^", 16, 2},
+				{token.IDENT, "d", 18, 2},
+				{token.SHIFTLEFT, "<<", 20, 2},
+				{token.INT, "1", 23, 2},
+				{token.SHIFTRIGHT, ">>", 25, 2},
+				{token.INT, "2", 28, 2},
+			},
+		},
 		{
 			name: "Simple Function",
 			in:   func1,
@@ -434,3 +518,212 @@ func TestLexer_NextToken(t *testing.T) {
 		})
 	}
 }
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		in      string
+		tokType token.TokenType
+		literal string
+	}{
+		{"42", token.INT, "42"},
+		{"0", token.INT, "0"},
+		{"1_000_000", token.INT, "1_000_000"},
+		{"0xFF", token.INT, "0xFF"},
+		{"0x_ff", token.INT, "0x_ff"},
+		{"0o17", token.INT, "0o17"},
+		{"0b1010", token.INT, "0b1010"},
+		{"3.14", token.FLOAT, "3.14"},
+		{"0.5", token.FLOAT, "0.5"},
+		{"1e10", token.FLOAT, "1e10"},
+		{"2.5E-3", token.FLOAT, "2.5E-3"},
+		{"1_000.000_1", token.FLOAT, "1_000.000_1"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.in)
+		tok := l.NextToken()
+		if tok.Type != tt.tokType || tok.Literal != tt.literal {
+			t.Errorf("%q: expected %s %q, got %s %q", tt.in, tt.tokType, tt.literal, tok.Type, tok.Literal)
+		}
+		if len(l.Errors()) != 0 {
+			t.Errorf("%q: unexpected errors: %v", tt.in, l.Errors())
+		}
+	}
+}
+
+func TestNullOperators(t *testing.T) {
+	input := "a?.b?[0] ?? null ? x.y"
+	expected := []struct {
+		tokType token.TokenType
+		literal string
+	}{
+		{token.IDENT, "a"},
+		{token.OPTDOT, "?."},
+		{token.IDENT, "b"},
+		{token.OPTINDEX, "?["},
+		{token.INT, "0"},
+		{token.RBRACK, "]"},
+		{token.COALESCE, "??"},
+		{token.NULL, "null"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "x"},
+		{token.STOP, "."},
+		{token.IDENT, "y"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.tokType || tok.Literal != tt.literal {
+			t.Fatalf("token %d: expected %s %q, got %s %q", i, tt.tokType, tt.literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestNumberLiteralErrors(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"0x", "line 1, col 3: hexadecimal literal has no digits after 0x prefix"},
+		{"0b102", "line 1, col 5: invalid digit '2' in binary literal"},
+		{"0o8", "line 1, col 3: invalid digit '8' in octal literal"},
+		{"1__0", "line 1, col 3: '_' must separate digits"},
+		{"1_", "line 1, col 2: '_' must separate digits"},
+		{"1e", "line 1, col 3: float exponent has no digits"},
+		{"123abc", "line 1, col 4: invalid character 'a' in decimal literal"},
+		{"0123", "line 1, col 1: leading zero in decimal literal; use 0o for octal"},
+		{"x = 1 @ 2", "line 1, col 7: illegal character '@'"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.in)
+		sawIllegal := false
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if tok.Type == token.ILLEGAL {
+				sawIllegal = true
+			}
+		}
+		if !sawIllegal {
+			t.Errorf("%q: expected an ILLEGAL token", tt.in)
+		}
+		errs := l.Errors()
+		if len(errs) != 1 || errs[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.in, tt.expected, errs)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{`"plain"`, "plain"},
+		{`"a\nb"`, "a\nb"},
+		{`"\ttab"`, "\ttab"},
+		{`"back\\slash"`, `back\slash`},
+		{`"ends in \\"`, `ends in \`},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"\u{48}\u{1F680}"`, "H\U0001F680"},
+		{`"héllo"`, "héllo"},
+		{"`raw \\n \"x\"`", `raw \n "x"`},
+		{"`a\r\nb`", "a\nb"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.in)
+		tok := l.NextToken()
+		if tok.Type != token.STRING || tok.Literal != tt.expected {
+			t.Errorf("%s: expected STRING %q, got %s %q", tt.in, tt.expected, tok.Type, tok.Literal)
+		}
+		if len(l.Errors()) != 0 {
+			t.Errorf("%s: unexpected errors: %v", tt.in, l.Errors())
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{`x = "abc`, "line 1, col 5: unterminated string literal"},
+		{"x = \"abc\ny = 1", "line 1, col 5: unterminated string literal"},
+		{`"abc\`, "line 1, col 1: unterminated string literal"},
+		{`"a\qb"`, "line 1, col 3: unknown escape sequence \\q"},
+		{`"\u41"`, "line 1, col 2: \\u escape must be written as \\u{hex}"},
+		{`"\u{}"`, "line 1, col 2: \\u escape must be written as \\u{hex}"},
+		{`"\u{D800}"`, "line 1, col 2: invalid code point U+D800 in \\u escape"},
+		{"x = 1\ny = `abc\ndef", "line 2, col 5: unterminated raw string literal"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.in)
+		sawIllegal := false
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if tok.Type == token.ILLEGAL {
+				sawIllegal = true
+			}
+		}
+		if !sawIllegal {
+			t.Errorf("%q: expected an ILLEGAL token", tt.in)
+		}
+		errs := l.Errors()
+		if len(errs) != 1 || errs[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.in, tt.expected, errs)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"x = \xff", "line 1, col 5: invalid UTF-8 encoding"},
+		{"x = \"ab\xc3\"", "line 1, col 8: invalid UTF-8 encoding in string literal"},
+		{"x = `\n\xe4\xb8`", "line 2, col 1: invalid UTF-8 encoding in string literal"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.in)
+		sawIllegal := false
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if tok.Type == token.ILLEGAL {
+				sawIllegal = true
+			}
+		}
+		if !sawIllegal {
+			t.Errorf("%q: expected an ILLEGAL token", tt.in)
+		}
+		errs := l.Errors()
+		if len(errs) != 1 || errs[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.in, tt.expected, errs)
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	input := "名前 = 1\nçà = \"é\""
+	l := New(input)
+	var toks []token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		toks = append(toks, tok)
+	}
+
+	for _, tok := range toks {
+		if tok.Type == token.NEWLINE || tok.Type == token.STRING {
+			continue
+		}
+		offset := l.Offset(tok.Line, tok.Col)
+		if !strings.HasPrefix(input[offset:], tok.Literal) {
+			t.Errorf("offset %d for %q at %d:%d points at %q", offset, tok.Literal, tok.Line, tok.Col, input[offset:])
+		}
+	}
+
+	if l.Offset(9, 1) != -1 {
+		t.Errorf("expected -1 for a line past the input, got %d", l.Offset(9, 1))
+	}
+}
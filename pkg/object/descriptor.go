@@ -0,0 +1,127 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Descriptor describes properties and methods that objects are
+// orchestrated from. Parameters are the properties set by an object's
+// construction arguments, and Properties the others, whose starting
+// values are in Defaults.
+type Descriptor struct {
+	Name       string
+	Parameters []string
+	Properties []string
+	Defaults   map[string]Object
+	Constants  map[string]Object
+	Methods    map[string]*Method
+	// Required holds the number of parameters of each method the
+	// descriptor declares without a body, which objects must provide
+	Required map[string]int
+	// Operators holds overloaded operators, such as + or [], and
+	// overloads of builtins such as len, keyed by name
+	Operators map[string]*Method
+	// Validators check new values of properties, keyed by property
+	Validators map[string]*Method
+	// Types holds the type annotation of each annotated property
+	Types map[string]string
+	// Init, if set, runs when an object the descriptor describes is
+	// built
+	Init *Method
+}
+
+func (d *Descriptor) Type() ObjectType { return DESCRIPTOR_OBJ }
+func (d *Descriptor) Inspect() string  { return "describe " + d.Name }
+
+// Declares reports whether name is a property, constant or method of d.
+func (d *Descriptor) Declares(name string) bool {
+	if _, ok := d.Defaults[name]; ok {
+		return true
+	}
+	if _, ok := d.Constants[name]; ok {
+		return true
+	}
+	if _, ok := d.Methods[name]; ok {
+		return true
+	}
+	if _, ok := d.Required[name]; ok {
+		return true
+	}
+	for _, param := range d.Parameters {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ObjectDefinition is an object orchestrated from Descriptors, with the
+// members of each merged together with its own. Calling it builds an
+// Instance, taking Parameters as arguments in order.
+type ObjectDefinition struct {
+	Name        string
+	Descriptors []*Descriptor
+	Parameters  []string
+	// Properties lists every property other than constants, in order
+	Properties []string
+	Defaults   map[string]Object
+	Constants  map[string]Object
+	Methods    map[string]*Method
+	Operators  map[string]*Method
+	Validators map[string]*Method
+	Types      map[string]string
+	// Inits are the descriptors' init methods, in order, followed by
+	// the object's own
+	Inits []*Method
+	Env   *Environment
+}
+
+func (od *ObjectDefinition) Type() ObjectType { return OBJECT_OBJ }
+func (od *ObjectDefinition) Inspect() string  { return "object " + od.Name }
+
+// Describes reports whether d is one of od's descriptors.
+func (od *ObjectDefinition) Describes(d *Descriptor) bool {
+	for _, desc := range od.Descriptors {
+		if desc == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Instance is an object built from an ObjectDefinition. Env holds its
+// properties, constants and methods, and is the scope its methods run
+// in. Its type is the name of its definition.
+type Instance struct {
+	Definition *ObjectDefinition
+	Env        *Environment
+	// Operators holds the definition's operators bound to the instance
+	Operators map[string]*Method
+}
+
+func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
+func (i *Instance) Inspect() string {
+	var out bytes.Buffer
+	var props []string
+	for _, name := range i.Definition.Properties {
+		value, _ := i.Env.GetLocal(name)
+		props = append(props, name+": "+value.Inspect())
+	}
+	out.WriteString(i.Definition.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(props, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// DescriptorView is how an object's methods see one of its descriptors,
+// so that Descriptor.Property reads the instance's value of a property
+// that descriptor declares.
+type DescriptorView struct {
+	Descriptor *Descriptor
+	Instance   *Instance
+}
+
+func (dv *DescriptorView) Type() ObjectType { return DESCRIPTOR_OBJ }
+func (dv *DescriptorView) Inspect() string  { return dv.Descriptor.Inspect() }
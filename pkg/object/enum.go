@@ -0,0 +1,58 @@
+package object
+
+import "sync/atomic"
+
+// enumValues counts the enum members created so far, giving each its
+// own hash key.
+var enumValues uint64
+
+// Enum is a set of named values, in the order they were declared.
+type Enum struct {
+	Name    string
+	Members []*EnumValue
+}
+
+// NewEnum returns an enum called name with the given members. Every
+// call gives new members, distinct from those of any other enum, even
+// one with the same name.
+func NewEnum(name string, members []string) *Enum {
+	enum := &Enum{Name: name}
+	for i, member := range members {
+		enum.Members = append(enum.Members, &EnumValue{
+			Enum:    enum,
+			Name:    member,
+			Ordinal: int64(i),
+			id:      atomic.AddUint64(&enumValues, 1),
+		})
+	}
+	return enum
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string  { return "enum " + e.Name }
+
+// Member returns the value of e called name.
+func (e *Enum) Member(name string) (*EnumValue, bool) {
+	for _, member := range e.Members {
+		if member.Name == name {
+			return member, true
+		}
+	}
+	return nil, false
+}
+
+// EnumValue is a member of an Enum. Each member has a single value, so
+// members can be compared by identity.
+type EnumValue struct {
+	Enum    *Enum
+	Name    string
+	Ordinal int64
+	id      uint64
+}
+
+func (ev *EnumValue) Type() ObjectType { return ENUM_VALUE_OBJ }
+func (ev *EnumValue) Inspect() string  { return ev.Enum.Name + "." + ev.Name }
+
+func (ev *EnumValue) HashKey() HashKey {
+	return HashKey{Type: ENUM_VALUE_OBJ, Value: ev.id}
+}
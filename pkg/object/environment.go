@@ -1,12 +1,61 @@
 package object
 
 type Environment struct {
-	store map[string]Object
+	store *store
 	outer *Environment
+	// block marks the scope of a loop body rather than a function
+	block bool
+	// yield is set on the scope of a running generator's body
+	yield func(Object)
+	// deferred holds the calls deferred by a method's body, on the
+	// method's scope
+	deferred []func() Object
+	// owner is set on the scope of an instance, to the instance
+	owner Object
+}
+
+// store holds a scope's variables in slots, numbered in the order the
+// names were first set, so that a name the resolver has placed can be
+// read by its slot instead of looked up.
+type store struct {
+	slots  map[string]int
+	names  []string
+	values []Object
+}
+
+func (s *store) get(name string) (Object, bool) {
+	slot, ok := s.slots[name]
+	if !ok {
+		return nil, false
+	}
+	return s.values[slot], true
+}
+
+// getSlot returns the value in slot, if it holds name.
+func (s *store) getSlot(slot int, name string) (Object, bool) {
+	if slot < 0 || slot >= len(s.names) || s.names[slot] != name {
+		return nil, false
+	}
+	return s.values[slot], true
+}
+
+func (s *store) set(name string, val Object) {
+	if slot, ok := s.slots[name]; ok {
+		s.values[slot] = val
+		return
+	}
+	s.slots[name] = len(s.names)
+	s.names = append(s.names, name)
+	s.values = append(s.values, val)
+}
+
+func (s *store) has(name string) bool {
+	_, ok := s.slots[name]
+	return ok
 }
 
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
+	s := &store{slots: make(map[string]int)}
 	return &Environment{store: s, outer: nil}
 }
 
@@ -16,15 +65,136 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// NewBlockEnvironment creates the scope for a loop body. Names declared
+// in it stay local to the block, but assigning to a name that already
+// exists in the enclosing function updates that variable.
+func NewBlockEnvironment(outer *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.block = true
+	return env
+}
+
+// WithOuter returns a scope that shares e's names, so that setting a
+// name in either is seen by both, but is enclosed by outer rather than
+// by e's own enclosing scope.
+func (e *Environment) WithOuter(outer *Environment) *Environment {
+	return &Environment{store: e.store, outer: outer, owner: e.owner}
+}
+
+// SetOwner marks e as the scope of the instance owner.
+func (e *Environment) SetOwner(owner Object) {
+	e.owner = owner
+}
+
+// Owner returns the instance whose member called name would be updated
+// by assigning to name in e. That is the instance a method belongs to,
+// when neither the method nor any block it is in has a variable called
+// name.
+func (e *Environment) Owner(name string) (Object, bool) {
+	for scope := e; scope != nil; scope = scope.outer {
+		if scope.store.has(name) {
+			return nil, false
+		}
+		if !scope.block {
+			if inst := scope.outer; inst != nil && inst.owner != nil {
+				if inst.store.has(name) {
+					return inst.owner, true
+				}
+			}
+			break
+		}
+	}
+	return nil, false
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
+	obj, ok := e.store.get(name)
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
 	return obj, ok
 }
 
+// GetAt returns the value of name from slot in the scope depth levels
+// out from e, as placed by the resolver. If that slot doesn't hold name,
+// because the variable hasn't been assigned yet or was assigned in a
+// different order than the resolver saw, it falls back to searching out
+// from e like Get.
+func (e *Environment) GetAt(depth, slot int, name string) (Object, bool) {
+	scope := e
+	for i := 0; i < depth && scope != nil; i++ {
+		scope = scope.outer
+	}
+	if scope == nil {
+		return e.Get(name)
+	}
+	if obj, ok := scope.store.getSlot(slot, name); ok {
+		return obj, ok
+	}
+	if obj, ok := scope.store.get(name); ok {
+		return obj, ok
+	}
+	return e.Get(name)
+}
+
+// GetLocal returns the value of name in e itself, without searching
+// enclosing scopes.
+func (e *Environment) GetLocal(name string) (Object, bool) {
+	return e.store.get(name)
+}
+
 func (e *Environment) Set(name string, val Object) Object {
-	e.store[name] = val
+	e.store.set(name, val)
 	return val
 }
+
+// Assign updates name in the nearest scope that declares it, searching
+// out through block scopes as far as the enclosing function. If no
+// such scope declares it, name is declared in e.
+func (e *Environment) Assign(name string, val Object) Object {
+	for scope := e; scope != nil; scope = scope.outer {
+		if scope.store.has(name) {
+			return scope.Set(name, val)
+		}
+		if !scope.block {
+			break
+		}
+	}
+	return e.Set(name, val)
+}
+
+// Defer registers call to be made when the method whose body e is part
+// of returns.
+func (e *Environment) Defer(call func() Object) {
+	scope := e
+	for scope.block && scope.outer != nil {
+		scope = scope.outer
+	}
+	scope.deferred = append(scope.deferred, call)
+}
+
+// Deferred returns the calls registered on e by Defer, in the order
+// they were deferred.
+func (e *Environment) Deferred() []func() Object {
+	return e.deferred
+}
+
+// SetYield makes e the body scope of a generator that hands values to
+// yield.
+func (e *Environment) SetYield(yield func(Object)) {
+	e.yield = yield
+}
+
+// Yield returns the yield function of the generator whose body e is
+// part of, or nil if e is not inside a generator.
+func (e *Environment) Yield() func(Object) {
+	for scope := e; scope != nil; scope = scope.outer {
+		if scope.yield != nil {
+			return scope.yield
+		}
+		if !scope.block {
+			break
+		}
+	}
+	return nil
+}
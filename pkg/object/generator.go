@@ -0,0 +1,173 @@
+package object
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Iterator produces a sequence of values one at a time. Next returns
+// false once the sequence is finished. Close stops the sequence early
+// and releases anything it holds; it is safe to call more than once.
+type Iterator interface {
+	Object
+	Next() (Object, bool)
+	Close()
+}
+
+// Generator is the lazy sequence returned by calling a method that
+// yields. The method body runs on its own goroutine, which hands each
+// yielded value back and then waits to be resumed, so only one side is
+// ever running at a time.
+type Generator struct {
+	state *generatorState
+}
+
+// generatorState is kept apart from Generator so the goroutine does not
+// keep the Generator reachable, which lets the finalizer find a
+// generator that was abandoned part way through and queue it for
+// CloseAbandoned. That only works when the Generator isn't reachable
+// from the body's own scope, such as a generator stored in a global
+// while its body reads globals; those have to be closed explicitly.
+type generatorState struct {
+	run     func(yield func(Object)) Object
+	values  chan Object
+	resume  chan bool
+	started bool
+	done    bool
+	// running is set while the body runs, so that it can't close its
+	// own generator and wait on itself
+	running bool
+}
+
+// stopGenerator unwinds a generator's goroutine when it is closed while
+// suspended in yield.
+type stopGenerator struct{}
+
+// IsStopGenerator reports whether r, a value recovered from a panic, is
+// a generator being closed. Anything that recovers panics in a generator
+// body must panic with r again so the generator can stop.
+func IsStopGenerator(r interface{}) bool {
+	_, ok := r.(stopGenerator)
+	return ok
+}
+
+// abandoned holds the generators that the finalizer found unreachable.
+// Closing one runs the rest of its body, including deferred calls, so it
+// is left to the interpreter rather than done on the finalizer's
+// goroutine.
+var abandoned struct {
+	sync.Mutex
+	states []*generatorState
+}
+
+// CloseAbandoned closes the generators that have become unreachable. It
+// must be called from the interpreter, between evaluations, so that the
+// bodies it unwinds don't run alongside other code.
+func CloseAbandoned() {
+	abandoned.Lock()
+	states := abandoned.states
+	abandoned.states = nil
+	abandoned.Unlock()
+
+	var busy []*generatorState
+	for _, s := range states {
+		if s.running {
+			// Its body is what called CloseAbandoned
+			busy = append(busy, s)
+			continue
+		}
+		s.close()
+	}
+	if len(busy) > 0 {
+		abandoned.Lock()
+		abandoned.states = append(abandoned.states, busy...)
+		abandoned.Unlock()
+	}
+}
+
+// NewGenerator returns a generator that calls run when its first value
+// is requested. run passes each value to yield, which returns once the
+// next value is wanted. A non-nil *Error returned by run is produced as
+// the generator's last value.
+func NewGenerator(run func(yield func(Object)) Object) *Generator {
+	g := &Generator{state: &generatorState{
+		run:    run,
+		values: make(chan Object),
+		resume: make(chan bool),
+	}}
+	runtime.SetFinalizer(g, func(g *Generator) {
+		abandoned.Lock()
+		abandoned.states = append(abandoned.states, g.state)
+		abandoned.Unlock()
+	})
+	return g
+}
+
+func (g *Generator) Type() ObjectType     { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string      { return "generator" }
+func (g *Generator) Next() (Object, bool) { return g.state.next() }
+func (g *Generator) Close()               { g.state.close() }
+
+func (s *generatorState) next() (Object, bool) {
+	if s.done {
+		return nil, false
+	}
+	// running is set before the body is started or resumed, so the
+	// body sees it
+	s.running = true
+	if !s.started {
+		s.started = true
+		go s.loop()
+	} else {
+		s.resume <- true
+	}
+
+	value, ok := <-s.values
+	s.running = false
+	if !ok {
+		s.done = true
+		return nil, false
+	}
+	if _, isErr := value.(*Error); isErr {
+		// The goroutine has finished without waiting to be resumed.
+		s.done = true
+	}
+	return value, true
+}
+
+func (s *generatorState) close() {
+	if s.running {
+		return
+	}
+	if !s.started || s.done {
+		s.done = true
+		return
+	}
+	s.done = true
+	s.resume <- false
+	for range s.values {
+	}
+}
+
+func (s *generatorState) loop() {
+	defer close(s.values)
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(stopGenerator); !ok {
+				s.values <- &Error{Message: fmt.Sprintf("internal error: %v", r)}
+			}
+		}
+	}()
+
+	if err, ok := s.run(s.yield).(*Error); ok {
+		s.values <- err
+	}
+}
+
+func (s *generatorState) yield(value Object) {
+	s.values <- value
+	if !<-s.resume {
+		panic(stopGenerator{})
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"github.com/alexjwhite-cb/jet/pkg/ast"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +19,7 @@ const (
 	NULL_OBJ         = "NULL"
 	ERROR_OBJ        = "ERROR"
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	METHOD_OBJ       = "METHOD"
@@ -23,6 +27,17 @@ const (
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	RANGE_OBJ        = "RANGE"
+	GENERATOR_OBJ    = "GENERATOR"
+	DESCRIPTOR_OBJ   = "DESCRIPTOR"
+	OBJECT_OBJ       = "OBJECT"
+	INSTANCE_OBJ     = "INSTANCE"
+	ENUM_OBJ         = "ENUM"
+	ENUM_VALUE_OBJ   = "ENUM_VALUE"
+
+	// BIG_INTEGER_KEY tags the hash keys of big integers, which hash
+	// their digits, so they can't collide with an Integer's raw value
+	BIG_INTEGER_KEY = "BIG_INTEGER"
 )
 
 type Object interface {
@@ -37,14 +52,25 @@ type Hashable interface {
 type Null struct{}
 
 func (n *Null) Inspect() string  { return "null" }
-func (n *Null) Type() ObjectType { return BOOLEAN_OBJ }
+func (n *Null) Type() ObjectType { return NULL_OBJ }
 
+// Error is a runtime error. Line and Col locate the expression that raised
+// it, and are zero until the evaluator has positioned it.
 type Error struct {
 	Message string
+	Line    int
+	Col     int
+	// Stack lists the calls the error unwound through, innermost first
+	Stack []string
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("ERROR: line %v, col %v: %s", e.Line, e.Col, e.Message)
+	}
+	return "ERROR: " + e.Message
+}
 
 type Integer struct {
 	Value int64
@@ -53,6 +79,40 @@ type Integer struct {
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
+// BigInteger holds an int that does not fit in 64 bits. It shares the
+// INTEGER type with Integer; use NewBigInteger so that every value has
+// exactly one representation.
+type BigInteger struct {
+	Value *big.Int
+}
+
+// NewBigInteger returns v as an *Integer if it fits in 64 bits, and
+// as a *BigInteger otherwise.
+func NewBigInteger(v *big.Int) Object {
+	if v.IsInt64() {
+		return &Integer{Value: v.Int64()}
+	}
+	return &BigInteger{Value: v}
+}
+
+func (b *BigInteger) Inspect() string  { return b.Value.String() }
+func (b *BigInteger) Type() ObjectType { return INTEGER_OBJ }
+
+type Float struct {
+	Value float64
+}
+
+// Inspect always shows a decimal point or exponent, so 1.0 is not
+// mistaken for the int 1.
+func (f *Float) Inspect() string {
+	out := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(out, ".eIN") {
+		out += ".0"
+	}
+	return out
+}
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
 type Boolean struct {
 	Value bool
 }
@@ -68,25 +128,31 @@ func (r *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (r *ReturnValue) Inspect() string  { return r.Value.Inspect() }
 
 type Method struct {
+	// Name is the name the method was declared with, or "" if it is
+	// anonymous
+	Name       string
 	Parameters []*ast.Ident
+	Defaults   []ast.Expr
+	Patterns   []ast.Target
+	// Types and ReturnType are the method's type annotations, as in
+	// ast.FuncLiteral
+	Types      []*ast.Ident
+	ReturnType *ast.Ident
 	Body       *ast.BlockStatement
 	Env        *Environment
+	// Generator is set when the body yields, so calling the method
+	// returns a *Generator instead of running the body
+	Generator bool
 }
 
 func (m *Method) Type() ObjectType { return METHOD_OBJ }
 func (m *Method) Inspect() string {
 	var out bytes.Buffer
-	var params []string
-
-	for _, p := range m.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString("meth")
 	out.WriteString(": ")
-	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(" {\n")
+	out.WriteString(ast.FormatParameters(m.Parameters, m.Defaults))
+	out.WriteString(" ")
 	out.WriteString(m.Body.String())
-	out.WriteString("\n}")
 	return out.String()
 }
 
@@ -122,8 +188,104 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+// Range is an arithmetic sequence of integers from Start towards End.
+// Elements are computed on demand, so a range of any length costs the
+// same to hold.
+type Range struct {
+	Start     int64
+	End       int64
+	Step      int64
+	Inclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	op := "..<"
+	if r.Inclusive {
+		op = ".."
+	}
+	out := fmt.Sprintf("%d%s%d", r.Start, op, r.End)
+	if r.Step != 1 {
+		out += fmt.Sprintf(" step %d", r.Step)
+	}
+	return out
+}
+
+// Len returns the number of elements in the range, saturating at the
+// largest int64.
+func (r *Range) Len() int64 {
+	var span, step uint64
+	switch {
+	case r.Step > 0 && (r.End > r.Start || r.Inclusive && r.End == r.Start):
+		span, step = uint64(r.End)-uint64(r.Start), uint64(r.Step)
+	case r.Step < 0 && (r.End < r.Start || r.Inclusive && r.End == r.Start):
+		span, step = uint64(r.Start)-uint64(r.End), -uint64(r.Step)
+	default:
+		return 0
+	}
+	if !r.Inclusive {
+		span--
+	}
+	n := span / step
+	if n >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(n) + 1
+}
+
+// At returns the element at index i, which must be in [0, Len()).
+func (r *Range) At(i int64) int64 {
+	return r.Start + i*r.Step
+}
+
+// Contains reports whether n is one of the range's elements.
+func (r *Range) Contains(n int64) bool {
+	length := r.Len()
+	if length == 0 {
+		return false
+	}
+	last := r.At(length - 1)
+	if r.Step > 0 {
+		if n < r.Start || n > last {
+			return false
+		}
+		return (uint64(n)-uint64(r.Start))%uint64(r.Step) == 0
+	}
+	if n > r.Start || n < last {
+		return false
+	}
+	return (uint64(r.Start)-uint64(n))%(-uint64(r.Step)) == 0
+}
+
+// HashMap maps keys to values, remembering the order keys were first
+// added in. Pairs should only be added with Set, which keeps that order.
 type HashMap struct {
 	Pairs map[HashKey]HashPair
+	// Keys holds the keys of Pairs in the order they were added
+	Keys []HashKey
+}
+
+// NewHashMap returns an empty map.
+func NewHashMap() *HashMap {
+	return &HashMap{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set adds pair to h under hashed. A key that is already in h keeps its
+// place and has its value replaced.
+func (h *HashMap) Set(hashed HashKey, pair HashPair) {
+	if _, ok := h.Pairs[hashed]; !ok {
+		h.Keys = append(h.Keys, hashed)
+	}
+	h.Pairs[hashed] = pair
+}
+
+// Ordered returns the pairs of h in the order their keys were added.
+func (h *HashMap) Ordered() []HashPair {
+	pairs := make([]HashPair, len(h.Keys))
+	for i, hashed := range h.Keys {
+		pairs[i] = h.Pairs[hashed]
+	}
+	return pairs
 }
 
 func (h *HashMap) Type() ObjectType { return HASH_OBJ }
@@ -131,7 +293,7 @@ func (h *HashMap) Inspect() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Ordered() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 	out.WriteString("{")
@@ -166,6 +328,23 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+func (f *Float) HashKey() HashKey {
+	if f.Value == 0 {
+		// -0.0 == 0.0, so both must hash alike
+		return HashKey{Type: f.Type(), Value: 0}
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
+func (b *BigInteger) HashKey() HashKey {
+	if b.Value.IsInt64() {
+		return (&Integer{Value: b.Value.Int64()}).HashKey()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+	return HashKey{Type: BIG_INTEGER_KEY, Value: h.Sum64()}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +24,169 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestBigIntegerHashKey(t *testing.T) {
+	n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	big1 := NewBigInteger(n)
+	big2 := NewBigInteger(new(big.Int).Set(n))
+	diff := NewBigInteger(new(big.Int).Add(n, big.NewInt(1)))
+
+	if big1.(Hashable).HashKey() != big2.(Hashable).HashKey() {
+		t.Errorf("big integers with same value have different hash keys")
+	}
+
+	if big1.(Hashable).HashKey() == diff.(Hashable).HashKey() {
+		t.Errorf("big integers with different values have same hash keys")
+	}
+
+	small := &Integer{Value: int64(big1.(Hashable).HashKey().Value)}
+	if big1.(Hashable).HashKey() == small.HashKey() {
+		t.Errorf("big integer has the same hash key as %d", small.Value)
+	}
+
+	fits := &BigInteger{Value: big.NewInt(42)}
+	if fits.HashKey() != (&Integer{Value: 42}).HashKey() {
+		t.Errorf("big integer that fits in 64 bits hashes differently from an integer")
+	}
+}
+
+func TestNewBigIntegerNormalises(t *testing.T) {
+	small := NewBigInteger(big.NewInt(42))
+	if _, ok := small.(*Integer); !ok {
+		t.Errorf("value that fits in 64 bits is not *Integer, got %T", small)
+	}
+
+	n, _ := new(big.Int).SetString("9223372036854775808", 10)
+	if _, ok := NewBigInteger(n).(*BigInteger); !ok {
+		t.Errorf("value that overflows 64 bits is not *BigInteger, got %T", NewBigInteger(n))
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-0.25, "-0.25"},
+		{1e21, "1e+21"},
+	}
+
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.expected {
+			t.Errorf("Inspect() of %g: expected %q, got %q", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestFloatHashKey(t *testing.T) {
+	zero := &Float{Value: 0}
+	negZero := &Float{Value: -zero.Value}
+	if zero.HashKey() != negZero.HashKey() {
+		t.Errorf("0.0 and -0.0 have different hash keys")
+	}
+
+	if (&Float{Value: 1.5}).HashKey() == (&Float{Value: 2.5}).HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+}
+
+func TestRangeLen(t *testing.T) {
+	tests := []struct {
+		r        Range
+		expected int64
+	}{
+		{Range{Start: 0, End: 10, Step: 1, Inclusive: true}, 11},
+		{Range{Start: 0, End: 10, Step: 1}, 10},
+		{Range{Start: 0, End: 10, Step: 3, Inclusive: true}, 4},
+		{Range{Start: 0, End: 9, Step: 3}, 3},
+		{Range{Start: 5, End: 5, Step: 1, Inclusive: true}, 1},
+		{Range{Start: 5, End: 5, Step: 1}, 0},
+		{Range{Start: 10, End: 0, Step: 1, Inclusive: true}, 0},
+		{Range{Start: 10, End: 0, Step: -2, Inclusive: true}, 6},
+		{Range{Start: 10, End: 0, Step: -2}, 5},
+		{Range{Start: math.MinInt64, End: math.MaxInt64, Step: 1, Inclusive: true}, math.MaxInt64},
+		{Range{Start: 0, End: math.MaxInt64, Step: math.MaxInt64, Inclusive: true}, 2},
+	}
+
+	for _, tt := range tests {
+		if got := tt.r.Len(); got != tt.expected {
+			t.Errorf("%s: expected length %d, got %d", tt.r.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestRangeContains(t *testing.T) {
+	tests := []struct {
+		r        Range
+		n        int64
+		expected bool
+	}{
+		{Range{Start: 0, End: 10, Step: 1}, 9, true},
+		{Range{Start: 0, End: 10, Step: 1}, 10, false},
+		{Range{Start: 0, End: 10, Step: 1, Inclusive: true}, 10, true},
+		{Range{Start: 0, End: 10, Step: 2}, 3, false},
+		{Range{Start: 0, End: 10, Step: 2}, 8, true},
+		{Range{Start: 10, End: 0, Step: -3, Inclusive: true}, 1, true},
+		{Range{Start: 10, End: 0, Step: -3, Inclusive: true}, 0, false},
+		{Range{Start: 0, End: 10, Step: 1}, -1, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.r.Contains(tt.n); got != tt.expected {
+			t.Errorf("%d in %s: expected %v, got %v", tt.n, tt.r.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestHashMapKeepsOrder(t *testing.T) {
+	hash := NewHashMap()
+	for _, key := range []string{"c", "a", "b", "a"} {
+		k := &String{Value: key}
+		hash.Set(k.HashKey(), HashPair{Key: k, Value: &Integer{Value: int64(len(hash.Keys))}})
+	}
+
+	if got, want := hash.Inspect(), "{c: 0, a: 3, b: 2}"; got != want {
+		t.Errorf("wrong order: want %s, got %s", want, got)
+	}
+}
+
+func TestEnvironmentGetAt(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+	outer.Set("b", &Integer{Value: 2})
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("b", &Integer{Value: 3})
+	outer.Set("a", &Integer{Value: 4})
+
+	tests := []struct {
+		depth, slot int
+		name        string
+		expected    int64
+	}{
+		{1, 0, "a", 4},
+		{1, 1, "b", 2},
+		{0, 0, "b", 3},
+		// A slot that holds another name falls back to the name
+		{1, 0, "b", 2},
+		{0, 5, "b", 3},
+		// A scope that doesn't hold the name falls back to Get
+		{0, 0, "a", 4},
+		{3, 0, "a", 4},
+	}
+
+	for _, tt := range tests {
+		obj, ok := inner.GetAt(tt.depth, tt.slot, tt.name)
+		if !ok {
+			t.Errorf("%s at %d/%d: not found", tt.name, tt.depth, tt.slot)
+			continue
+		}
+		if got := obj.(*Integer).Value; got != tt.expected {
+			t.Errorf("%s at %d/%d: want %d, got %d", tt.name, tt.depth, tt.slot, tt.expected, got)
+		}
+	}
+	if _, ok := inner.GetAt(0, 0, "c"); ok {
+		t.Errorf("c found, but was never set")
+	}
+}
@@ -1,10 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"github.com/alexjwhite-cb/jet/pkg/ast"
 	"github.com/alexjwhite-cb/jet/pkg/lexer"
 	"github.com/alexjwhite-cb/jet/pkg/token"
+	"math/big"
 	"strconv"
 )
 
@@ -13,10 +15,13 @@ const (
 	LOWEST
 	POSTFIX  // ->
 	EQUALS   // == or !=
-	LESSMORE // < or >
-	SUM      // + or -
-	PRODUCT  // * or /
-	PREFIX   // -x or !x
+	LESSMORE // < or > or in
+	COALESCE // ??
+	RANGE    // .. or ..<
+	SUM      // + or - or | or ^
+	PRODUCT  // * or / or % or & or << or >>
+	PREFIX   // -x or !x or ~x
+	POWER    // x ** y
 	CALL     // myFunction()
 	INDEX    // array[index]
 )
@@ -28,13 +33,28 @@ var priority = map[token.TokenType]int{
 	token.LESSOREQUAL: EQUALS,
 	token.LESSTHAN:    LESSMORE,
 	token.MORETHAN:    LESSMORE,
+	token.IN:          LESSMORE,
+	token.IS:          LESSMORE,
+	token.RANGE:       RANGE,
+	token.RANGEEXCL:   RANGE,
 	token.PLUS:        SUM,
 	token.MINUS:       SUM,
+	token.BITOR:       SUM,
+	token.BITXOR:      SUM,
 	token.MULTIPLY:    PRODUCT,
 	token.DIVIDE:      PRODUCT,
+	token.MODULO:      PRODUCT,
+	token.BITAND:      PRODUCT,
+	token.SHIFTLEFT:   PRODUCT,
+	token.SHIFTRIGHT:  PRODUCT,
+	token.POWER:       POWER,
 	token.LPAREN:      CALL,
 	token.PASSTHROUGH: POSTFIX,
 	token.LBRACK:      INDEX,
+	token.OPTINDEX:    INDEX,
+	token.STOP:        INDEX,
+	token.OPTDOT:      INDEX,
+	token.COALESCE:    COALESCE,
 }
 
 type (
@@ -61,6 +81,12 @@ type Parser struct {
 	infixParseFns   map[token.TokenType]infixParseFn
 	postfixParseFns map[token.TokenType]postfixParseFn
 	errors          []string
+	// rangeEnd is set while parsing the end of a range, where the
+	// contextual keyword step may follow
+	rangeEnd bool
+	// yields has an entry for each method literal being parsed,
+	// innermost last, recording whether its body yields
+	yields []bool
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -72,12 +98,20 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentity)
 	p.registerPrefix(token.INT, p.parseIntLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BITNOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNull)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.ELLIPSIS, p.parseSpreadExpression)
+	p.registerPrefix(token.YIELD, p.parseYieldExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.ERROR, p.parseErrorExpression)
 	p.registerPrefix(token.METHOD, p.parseFuncLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACK, p.parseArrayLiteral)
@@ -89,12 +123,27 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LESSOREQUAL, p.parseInfixExpression)
 	p.registerInfix(token.LESSTHAN, p.parseInfixExpression)
 	p.registerInfix(token.MORETHAN, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.IS, p.parseIsExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.MULTIPLY, p.parseInfixExpression)
 	p.registerInfix(token.DIVIDE, p.parseInfixExpression)
+	p.registerInfix(token.MODULO, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.BITAND, p.parseInfixExpression)
+	p.registerInfix(token.BITOR, p.parseInfixExpression)
+	p.registerInfix(token.BITXOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFTLEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFTRIGHT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACK, p.parseIndexExpression)
+	p.registerInfix(token.OPTINDEX, p.parseOptionalIndexExpression)
+	p.registerInfix(token.STOP, p.parseMemberExpression)
+	p.registerInfix(token.OPTDOT, p.parseMemberExpression)
+	p.registerInfix(token.COALESCE, p.parseInfixExpression)
+	p.registerInfix(token.RANGE, p.parseRangeExpression)
+	p.registerInfix(token.RANGEEXCL, p.parseRangeExpression)
 	p.postfixParseFns = make(map[token.TokenType]postfixParseFn)
 	p.registerPostfix(token.PASSTHROUGH, p.parseReturnStatement)
 
@@ -130,7 +179,30 @@ func (p *Parser) parseStatement() ast.Stmt {
 		if p.peekTokenIs(token.ASSIGN) {
 			return p.parseValueStatement()
 		}
-		exp = p.parseExpressionStatement()
+		stmt := p.parseExpressionStatement()
+		if member, ok := stmt.Expression.(*ast.MemberExpression); ok && !member.Optional && p.peekTokenIs(token.ASSIGN) {
+			return p.parsePropertyStatement(member)
+		}
+		exp = stmt
+	case token.LBRACK, token.LBRACE:
+		stmt := p.parseExpressionStatement()
+		if p.peekTokenIs(token.ASSIGN) {
+			return p.parseDestructuringStatement(stmt)
+		}
+		exp = stmt
+	case token.FOR:
+		return p.parseForStatement()
+	case token.DEFER:
+		return p.parseDeferStatement()
+	case token.DESCRIBE:
+		return p.parseDescribeStatement()
+	case token.OBJECT:
+		return p.parseObjectStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
+	case token.NEWLINE:
+		// A blank line, or the line break after a block.
+		return nil
 	default:
 		exp = p.parseExpressionStatement()
 	}
@@ -147,13 +219,158 @@ func (p *Parser) parseValueStatement() *ast.ValueStmt {
 	}
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
-	for !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.NEWLINE) && !p.curTokenIs(token.EOF) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
 	return stmt
 }
 
+// parsePropertyStatement parses target = value, where target has
+// already been parsed.
+func (p *Parser) parsePropertyStatement(target *ast.MemberExpression) *ast.PropertyStmt {
+	stmt := &ast.PropertyStmt{Token: p.peekToken, Target: target}
+	p.nextToken()
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseDestructuringStatement parses pattern = value, where left has
+// already been parsed as the array or map literal that the pattern is
+// written as.
+func (p *Parser) parseDestructuringStatement(left *ast.ExpressionStmt) ast.Stmt {
+	target := p.toTarget(left.Expression, left.Token)
+	if target == nil {
+		return nil
+	}
+	stmt := &ast.ValueStmt{Token: left.Token, Target: target}
+	p.nextToken()
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseTarget parses a name or a destructuring pattern, starting at
+// its first token.
+func (p *Parser) parseTarget() ast.Target {
+	tok := p.curToken
+	switch tok.Type {
+	case token.IDENT:
+		return &ast.Ident{Token: tok, Value: tok.Literal}
+	case token.LBRACK:
+		return p.toTarget(p.parseArrayLiteral(), tok)
+	case token.LBRACE:
+		return p.toTarget(p.parseHashMap(), tok)
+	}
+	p.errorAt(tok, "expected IDENT, got %s", tok.Type)
+	return nil
+}
+
+// parseLoopVariables parses the value, or key, value, after for. The
+// key is a name and the value may be a destructuring pattern.
+func (p *Parser) parseLoopVariables() (*ast.Ident, ast.Target, bool) {
+	p.nextToken()
+	value := p.parseTarget()
+	if value == nil {
+		return nil, nil, false
+	}
+	key, ok := value.(*ast.Ident)
+	if !ok || !p.peekTokenIs(token.COMMA) {
+		return nil, value, true
+	}
+
+	p.nextToken()
+	p.nextToken()
+	value = p.parseTarget()
+	if value == nil {
+		return nil, nil, false
+	}
+	return key, value, true
+}
+
+// toTarget converts an expression that was parsed as an array or map
+// literal into the destructuring pattern it spells out. Errors are
+// reported at tok, the literal that contains exp.
+func (p *Parser) toTarget(exp ast.Expr, tok token.Token) ast.Target {
+	switch exp := exp.(type) {
+	case *ast.Ident:
+		return exp
+
+	case *ast.ArrayLiteral:
+		target := &ast.ArrayTarget{Token: exp.Token}
+		for i, el := range exp.Elements {
+			if spread, ok := el.(*ast.SpreadExpression); ok {
+				rest := p.restTarget(spread, i == len(exp.Elements)-1)
+				if rest == nil {
+					return nil
+				}
+				target.Rest = rest
+				continue
+			}
+			elTarget := p.toTarget(el, exp.Token)
+			if elTarget == nil {
+				return nil
+			}
+			target.Elements = append(target.Elements, elTarget)
+		}
+		return target
+
+	case *ast.HashMap:
+		target := &ast.MapTarget{Token: exp.Token}
+		for i, key := range exp.Keys {
+			if spread, ok := key.(*ast.SpreadExpression); ok {
+				rest := p.restTarget(spread, i == len(exp.Keys)-1)
+				if rest == nil {
+					return nil
+				}
+				target.Rest = rest
+				continue
+			}
+			switch key.(type) {
+			case *ast.StringLiteral, *ast.IntLiteral, *ast.Boolean:
+			default:
+				p.errorAt(exp.Token, "cannot use %s as a key in a destructuring pattern", key.String())
+				return nil
+			}
+			value := p.toTarget(exp.Pairs[key], exp.Token)
+			if value == nil {
+				return nil
+			}
+			target.Keys = append(target.Keys, key)
+			target.Values = append(target.Values, value)
+		}
+		return target
+
+	case nil:
+		// The literal failed to parse and has reported why
+		return nil
+	}
+	p.errorAt(tok, "cannot assign to %s", exp.String())
+	return nil
+}
+
+// restTarget checks that a ...name in a pattern names a variable and
+// comes last.
+func (p *Parser) restTarget(spread *ast.SpreadExpression, last bool) *ast.Ident {
+	rest, ok := spread.Value.(*ast.Ident)
+	if !ok {
+		p.errorAt(spread.Token, "cannot assign to %s", spread.String())
+		return nil
+	}
+	if !last {
+		p.errorAt(spread.Token, "...%s must be the last element of a destructuring pattern", rest.Value)
+		return nil
+	}
+	return rest
+}
+
 func (p *Parser) parseReturnStatement(left ast.Stmt) ast.Stmt {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 	expr, ok := left.(*ast.ExpressionStmt)
@@ -178,6 +395,12 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStmt {
 func (p *Parser) parseIntLiteral() ast.Expr {
 	lit := &ast.IntLiteral{Token: p.curToken}
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if n, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			lit.Big = n
+			return lit
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("line %v, col %v: could not parse %q as integer",
 			p.curToken.Line, p.curToken.Col, p.curToken.Literal)
@@ -188,16 +411,78 @@ func (p *Parser) parseIntLiteral() ast.Expr {
 	return lit
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expr {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("line %v, col %v: could not parse %q as float",
+			p.curToken.Line, p.curToken.Col, p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
 func (p *Parser) parseStringLiteral() ast.Expr {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseArrayLiteral parses [a, b, ...], or a comprehension
+// [element for x in iterable if condition].
 func (p *Parser) parseArrayLiteral() ast.Expr {
-	array := &ast.ArrayLiteral{Token: p.curToken}
-	array.Elements = p.parseExpressionList(token.RBRACK)
+	tok := p.curToken
+	if p.peekTokenIs(token.RBRACK) {
+		p.nextToken()
+		return &ast.ArrayLiteral{Token: tok, Elements: []ast.Expr{}}
+	}
+
+	p.nextToken()
+	first := p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.FOR) {
+		p.nextToken()
+		clause := p.parseComprehensionClause()
+		if clause == nil || !p.expectPeek(token.RBRACK) {
+			return nil
+		}
+		return &ast.ArrayComprehension{Token: tok, Element: first, Clause: clause}
+	}
+
+	array := &ast.ArrayLiteral{Token: tok, Elements: []ast.Expr{first}}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		array.Elements = append(array.Elements, p.parseExpression(LOWEST))
+	}
+	if !p.expectPeek(token.RBRACK) {
+		return nil
+	}
 	return array
 }
 
+// parseComprehensionClause parses for x in iterable if condition, or
+// for key, value in ..., starting at the for.
+func (p *Parser) parseComprehensionClause() *ast.ComprehensionClause {
+	clause := &ast.ComprehensionClause{Token: p.curToken}
+	var ok bool
+	if clause.Key, clause.Value, ok = p.parseLoopVariables(); !ok {
+		return nil
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	clause.Iterable = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		clause.Condition = p.parseExpression(LOWEST)
+	}
+	return clause
+}
+
 func (p *Parser) parseHashMap() ast.Expr {
 	hash := &ast.HashMap{Token: p.curToken}
 	hash.Pairs = make(map[ast.Expr]ast.Expr)
@@ -212,14 +497,37 @@ hashLoop:
 			p.nextToken()
 		}
 		key := p.parseExpression(LOWEST)
+		_, isSpread := key.(*ast.SpreadExpression)
+		ident, isIdent := key.(*ast.Ident)
+		switch {
+		case isSpread:
+			hash.Keys = append(hash.Keys, key)
+			hash.Pairs[key] = nil
+		case isIdent && (p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.RBRACE)):
+			// {name} is short for {"name": name}
+			tok := ident.Token
+			tok.Type = token.STRING
+			key = &ast.StringLiteral{Token: tok, Value: ident.Value}
+			hash.Keys = append(hash.Keys, key)
+			hash.Pairs[key] = ident
+		default:
+			hash.Keys = append(hash.Keys, key)
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
 
-		if !p.expectPeek(token.COLON) {
-			return nil
-		}
+			p.nextToken()
+			hash.Pairs[key] = p.parseExpression(LOWEST)
 
-		p.nextToken()
-		value := p.parseExpression(LOWEST)
-		hash.Pairs[key] = value
+			if len(hash.Keys) == 1 && p.peekTokenIs(token.FOR) {
+				p.nextToken()
+				clause := p.parseComprehensionClause()
+				if clause == nil || !p.expectPeek(token.RBRACE) {
+					return nil
+				}
+				return &ast.MapComprehension{Token: hash.Token, Key: key, Value: hash.Pairs[key], Clause: clause}
+			}
+		}
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
@@ -231,10 +539,11 @@ hashLoop:
 }
 
 func (p *Parser) noPrefixParseFnError(t token.Token) {
-	errToken := fmt.Sprintf("%s", t.Type)
 	if t.Type == token.ILLEGAL {
-		errToken = fmt.Sprintf("%s (%s)", t.Type, t.Literal)
+		// The lexer has already reported why the token is illegal
+		return
 	}
+	errToken := fmt.Sprintf("%s", t.Type)
 	msg := fmt.Sprintf("line %v, col %v: no prefix parse function for %s found", t.Line, t.Col, errToken)
 	p.errors = append(p.errors, msg)
 }
@@ -263,7 +572,8 @@ func (p *Parser) parseExpression(prio int) ast.Expr {
 
 	// Temporarily handle a scenario like: x 4 "hello"
 	switch {
-	case p.peekTokenIs(token.IDENT), p.peekTokenIs(token.INT), p.peekTokenIs(token.STRING):
+	case p.rangeEnd && p.peekIsStep():
+	case p.peekTokenIs(token.IDENT), p.peekTokenIs(token.INT), p.peekTokenIs(token.FLOAT), p.peekTokenIs(token.STRING):
 		msg := fmt.Sprintf("line %v, col %v: no operator found between %q and %q",
 			p.curToken.Line, p.curToken.Col, p.curToken.Literal, p.peekToken.Literal)
 		p.errors = append(p.errors, msg)
@@ -305,11 +615,74 @@ func (p *Parser) parseInfixExpression(left ast.Expr) ast.Expr {
 		Left:     left,
 	}
 	prio := p.curPriority()
+	if p.curTokenIs(token.POWER) {
+		// ** is right-associative, so 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		prio--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(prio)
 	return expression
 }
 
+// parseSpreadExpression parses ...value. The spread takes the whole
+// expression after it, so ...0..3 spreads the range.
+func (p *Parser) parseSpreadExpression() ast.Expr {
+	exp := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// parseRangeExpression parses start..end or start..<end, followed by an
+// optional "step n". step is only special in this position, so it can
+// still be used as a name elsewhere.
+func (p *Parser) parseRangeExpression(start ast.Expr) ast.Expr {
+	exp := &ast.RangeExpression{
+		Token:     p.curToken,
+		Start:     start,
+		Inclusive: p.curTokenIs(token.RANGE),
+	}
+	prio := p.curPriority()
+	p.nextToken()
+	p.rangeEnd = true
+	exp.End = p.parseExpression(prio)
+	p.rangeEnd = false
+
+	if p.peekIsStep() {
+		p.nextToken()
+		p.nextToken()
+		exp.Step = p.parseExpression(prio)
+	}
+	return exp
+}
+
+func (p *Parser) peekIsStep() bool {
+	return p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "step"
+}
+
+func (p *Parser) parseForStatement() ast.Stmt {
+	stmt := &ast.ForStatement{Token: p.curToken}
+	var ok bool
+	if stmt.Key, stmt.Value, ok = p.parseLoopVariables(); !ok {
+		return nil
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
 func (p *Parser) parsePostfixExpressionStatement(left ast.Stmt) ast.Stmt {
 	postfix := p.postfixParseFns[p.curToken.Type]
 	if postfix == nil {
@@ -319,41 +692,105 @@ func (p *Parser) parsePostfixExpressionStatement(left ast.Stmt) ast.Stmt {
 	return stmt
 }
 
+func (p *Parser) parseNull() ast.Expr {
+	return &ast.Null{Token: p.curToken}
+}
+
+// parseMemberExpression parses left.name or left?.name.
+func (p *Parser) parseMemberExpression(left ast.Expr) ast.Expr {
+	exp := &ast.MemberExpression{Token: p.curToken, Left: left, Optional: p.curTokenIs(token.OPTDOT)}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
+}
+
+// parseOptionalIndexExpression parses left?[index] or left?[low:high].
+func (p *Parser) parseOptionalIndexExpression(left ast.Expr) ast.Expr {
+	switch exp := p.parseIndexExpression(left).(type) {
+	case *ast.IndexExpression:
+		exp.Optional = true
+		return exp
+	case *ast.SliceExpression:
+		exp.Optional = true
+		return exp
+	}
+	return nil
+}
+
+// parseIndexExpression parses left[index], or a slice left[low:high]
+// where either bound may be left out.
 func (p *Parser) parseIndexExpression(left ast.Expr) ast.Expr {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	tok := p.curToken
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
 
+	var index ast.Expr
+	if !p.curTokenIs(token.COLON) {
+		index = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACK) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: tok, Left: left, Index: index}
+		}
+		p.nextToken()
+	}
+
+	slice := &ast.SliceExpression{Token: tok, Left: left, Low: index}
+	if p.peekTokenIs(token.RBRACK) {
+		p.nextToken()
+		return slice
+	}
+	p.nextToken()
+	slice.High = p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RBRACK) {
 		return nil
 	}
-	return exp
+	return slice
 }
 
 func (p *Parser) parseCallExpression(function ast.Expr) ast.Expr {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Args = p.parseCallArguments()
+	exp.Args, exp.Names = p.parseCallArguments()
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expr {
+// parseCallArguments parses positional arguments followed by any named
+// ones, written name: value.
+func (p *Parser) parseCallArguments() ([]ast.Expr, []*ast.Ident) {
 	var args []ast.Expr
+	var names []*ast.Ident
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return args
+		return args, names
 	}
 
-	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+	named := false
+	for {
 		p.nextToken()
+		var name *ast.Ident
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+			named = true
+			p.nextToken()
+			p.nextToken()
+		} else if named {
+			p.errorAt(p.curToken, "positional argument follows named argument")
+			return nil, nil
+		}
 		args = append(args, p.parseExpression(LOWEST))
+		names = append(names, name)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return nil, nil
 	}
-	return args
+	return args, names
 }
 
 func (p *Parser) parseIdentity() ast.Expr {
@@ -373,28 +810,6 @@ func (p *Parser) parseGroupedExpression() ast.Expr {
 	return exp
 }
 
-func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expr {
-	list := []ast.Expr{}
-
-	if p.peekTokenIs(end) {
-		p.nextToken()
-		return list
-	}
-
-	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
-	}
-
-	if !p.expectPeek(end) {
-		return nil
-	}
-	return list
-}
-
 func (p *Parser) parseIfExpression() ast.Expr {
 	expression := &ast.IfExpression{Token: p.curToken}
 	p.nextToken()
@@ -416,23 +831,580 @@ func (p *Parser) parseIfExpression() ast.Expr {
 	return expression
 }
 
+// parseMatchExpression parses match subject { arm, ... }. Arms are
+// separated by commas, newlines, or both.
+func (p *Parser) parseMatchExpression() ast.Expr {
+	exp := &ast.MatchExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+	p.skipNewlines()
+
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			msg := fmt.Sprintf("line %v, col %v: match is missing its closing }",
+				exp.Token.Line, exp.Token.Col)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+
+		arm := &ast.MatchArm{Token: p.curToken}
+		arm.Pattern = p.parsePattern()
+		if arm.Pattern == nil {
+			return nil
+		}
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			arm.Guard = p.parseExpression(LOWEST)
+		}
+		if !p.expectPeek(token.PASSTHROUGH) {
+			return nil
+		}
+		p.nextToken()
+		arm.Body = p.parseExpression(LOWEST)
+		exp.Arms = append(exp.Arms, arm)
+
+		p.nextToken()
+		if p.curTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+		p.skipNewlines()
+	}
+	return exp
+}
+
+// parsePattern parses a single match pattern starting at curToken.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		// Enum.Member, or another value read from a name
+		if p.peekTokenIs(token.STOP) {
+			pattern := &ast.ValuePattern{Token: p.curToken}
+			if pattern.Value = p.parseExpression(LOWEST); pattern.Value == nil {
+				return nil
+			}
+			return pattern
+		}
+		name := &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekTypeName() {
+			p.nextToken()
+			typeName := &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+			return &ast.TypePattern{Token: name.Token, Name: name, TypeName: typeName}
+		}
+		if name.Value == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		return &ast.BindingPattern{Token: p.curToken, Name: name}
+
+	case token.LBRACK:
+		return p.parseArrayPattern()
+
+	case token.LBRACE:
+		return p.parseHashPattern()
+
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.NULL, token.MINUS:
+		pattern := &ast.ValuePattern{Token: p.curToken}
+		pattern.Value = p.parseExpression(LOWEST)
+		if pattern.Value == nil {
+			return nil
+		}
+		return pattern
+
+	default:
+		msg := fmt.Sprintf("line %v, col %v: unexpected %q in match pattern",
+			p.curToken.Line, p.curToken.Col, p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACK) {
+		p.nextToken()
+		if pattern.Rest != nil {
+			msg := fmt.Sprintf("line %v, col %v: ...%s must be the last element of an array pattern",
+				pattern.Rest.Token.Line, pattern.Rest.Token.Col, pattern.Rest.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.MULTIPLY) {
+			p.errorAt(p.curToken, "write the rest of an array pattern as ...%s", p.curToken.Literal)
+			return nil
+		}
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+		} else {
+			element := p.parsePattern()
+			if element == nil {
+				return nil
+			}
+			pattern.Elements = append(pattern.Elements, element)
+		}
+		if !p.peekTokenIs(token.RBRACK) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+		if key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parsePattern()
+		if value == nil {
+			return nil
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pattern
+}
+
+func (p *Parser) skipNewlines() {
+	for p.curTokenIs(token.NEWLINE) {
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseFuncLiteral() ast.Expr {
 	lit := &ast.FuncLiteral{Token: p.curToken}
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lit.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.parseMethodBody(lit) {
+		return nil
+	}
+	return lit
+}
+
+// parseMethodBody parses the optional : parameters and the { body } of
+// a method, after its name.
+func (p *Parser) parseMethodBody(lit *ast.FuncLiteral) bool {
+	return p.parseMethodParameters(lit) && p.parseMethodBlock(lit)
+}
+
+// parseMethodParameters parses a method's : parameters, if it has any,
+// and its -> return type, if it is annotated with one.
+func (p *Parser) parseMethodParameters(lit *ast.FuncLiteral) bool {
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken()
-		lit.Parameters = p.parseFunctionParameters()
-	} else {
-		lit.Parameters = nil
+		if !p.parseFunctionParameters(lit) {
+			return false
+		}
 	}
+	if p.peekTokenIs(token.PASSTHROUGH) {
+		p.nextToken()
+		if !p.expectTypeName() {
+			return false
+		}
+		lit.ReturnType = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	return true
+}
 
-	if !p.expectPeek(token.LBRACE) {
+// parseTypeAnnotation parses the type name that can follow a parameter
+// or property name, returning nil if there isn't one.
+func (p *Parser) parseTypeAnnotation() *ast.Ident {
+	if !p.peekTypeName() {
 		return nil
 	}
+	p.nextToken()
+	return &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// peekTypeName reports whether peekToken can name a type: an identifier,
+// or null, which is a keyword.
+func (p *Parser) peekTypeName() bool {
+	return p.peekTokenIs(token.IDENT) || p.peekTokenIs(token.NULL)
+}
+
+// expectTypeName is expectPeek for a type name.
+func (p *Parser) expectTypeName() bool {
+	if p.peekTypeName() {
+		p.nextToken()
+		return true
+	}
+	p.peekError(token.IDENT, p.peekToken)
+	return false
+}
 
+// parseMethodBlock parses a method's { body }.
+func (p *Parser) parseMethodBlock(lit *ast.FuncLiteral) bool {
+	if !p.expectPeek(token.LBRACE) {
+		return false
+	}
+
+	p.yields = append(p.yields, false)
 	lit.Body = p.parseBlockStatement()
+	lit.IsGenerator = p.yields[len(p.yields)-1]
+	p.yields = p.yields[:len(p.yields)-1]
+	return true
+}
+
+// operatorParameters is the number of parameters taken by the method
+// that overloads each operator.
+var operatorParameters = map[token.TokenType]int{
+	token.PLUS:        1,
+	token.MINUS:       1,
+	token.MULTIPLY:    1,
+	token.DIVIDE:      1,
+	token.MODULO:      1,
+	token.POWER:       1,
+	token.EQUAL:       1,
+	token.NOTEQUAL:    1,
+	token.LESSTHAN:    1,
+	token.MORETHAN:    1,
+	token.LESSOREQUAL: 1,
+	token.MOREOREQUAL: 1,
+	token.LBRACK:      1,
+}
+
+// parseDescribeStatement parses describe Name: Param, ... { members }.
+func (p *Parser) parseDescribeStatement() ast.Stmt {
+	stmt := &ast.DescribeStatement{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		var ok bool
+		if stmt.Parameters, ok = p.parseNameList("parameter", &stmt.Types); !ok {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) || !p.parseMembers(stmt.Token, &stmt.Members) {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseObjectStatement parses object Name: Descriptor, ... { members }.
+func (p *Parser) parseObjectStatement() ast.Stmt {
+	stmt := &ast.ObjectStatement{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		var ok bool
+		if stmt.Descriptors, ok = p.parseNameList("descriptor", nil); !ok {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) || !p.parseMembers(stmt.Token, &stmt.Members) {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseEnumStatement parses enum Name { Member, ... }. Members can also
+// be separated by line breaks.
+func (p *Parser) parseEnumStatement() ast.Stmt {
+	stmt := &ast.EnumStatement{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	p.nextToken()
+	p.skipNewlines()
+	for !p.curTokenIs(token.RBRACE) {
+		switch {
+		case p.curTokenIs(token.EOF):
+			p.errorAt(stmt.Token, "enum is missing its closing }")
+			return nil
+		case !p.curTokenIs(token.IDENT):
+			p.errorAt(p.curToken, "unexpected %q in enum body", p.curToken.Literal)
+			return nil
+		case seen[p.curToken.Literal]:
+			p.errorAt(p.curToken, "duplicate member %s", p.curToken.Literal)
+			return nil
+		}
+		seen[p.curToken.Literal] = true
+		stmt.Members = append(stmt.Members, &ast.Ident{Token: p.curToken, Value: p.curToken.Literal})
+
+		p.nextToken()
+		if p.curTokenIs(token.COMMA) || p.curTokenIs(token.NEWLINE) {
+			p.nextToken()
+			p.skipNewlines()
+		} else if !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+			p.errorAt(p.curToken, "expected , or }, got %q", p.curToken.Literal)
+			return nil
+		}
+	}
+	if len(stmt.Members) == 0 {
+		p.errorAt(stmt.Name.Token, "enum %s needs at least one member", stmt.Name.Value)
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseNameList parses name, name, ... where each name is different.
+// If types is not nil, each name can be followed by a type annotation,
+// which is added to types.
+func (p *Parser) parseNameList(kind string, types *[]*ast.Ident) ([]*ast.Ident, bool) {
+	var names []*ast.Ident
+	seen := make(map[string]bool)
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil, false
+		}
+		name := &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[name.Value] {
+			p.errorAt(name.Token, "duplicate %s %s", kind, name.Value)
+			return nil, false
+		}
+		seen[name.Value] = true
+		names = append(names, name)
+		if types != nil {
+			*types = append(*types, p.parseTypeAnnotation())
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			return names, true
+		}
+		p.nextToken()
+	}
+}
+
+// parseMembers parses the properties, methods, overloads and validators in
+// the body of a descriptor or object, up to its closing brace. start is
+// the describe or object keyword.
+func (p *Parser) parseMembers(start token.Token, members *ast.Members) bool {
+	for {
+		p.nextToken()
+		switch p.curToken.Type {
+		case token.NEWLINE, token.SEMICOLON:
+
+		case token.RBRACE:
+			return true
+
+		case token.EOF:
+			p.errorAt(start, "%s is missing its closing }", start.Literal)
+			return false
+
+		case token.IDENT, token.CONST:
+			prop := &ast.Property{Token: p.curToken, Const: p.curTokenIs(token.CONST)}
+			if prop.Const && !p.expectPeek(token.IDENT) {
+				return false
+			}
+			prop.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+			prop.Type = p.parseTypeAnnotation()
+			if !p.expectPeek(token.ASSIGN) {
+				return false
+			}
+			p.nextToken()
+			if prop.Value = p.parseExpression(LOWEST); prop.Value == nil {
+				return false
+			}
+			members.Properties = append(members.Properties, prop)
+
+		case token.METHOD:
+			lit := &ast.FuncLiteral{Token: p.curToken}
+			if !p.peekTokenIs(token.IDENT) {
+				p.errorAt(lit.Token, "a method in a %s needs a name", start.Literal)
+				return false
+			}
+			p.nextToken()
+			lit.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.parseMethodParameters(lit) {
+				return false
+			}
+			// A descriptor can require a method by declaring it
+			// without a body.
+			if start.Type == token.DESCRIBE && !p.peekTokenIs(token.LBRACE) {
+				members.Required = append(members.Required, lit)
+				continue
+			}
+			if !p.parseMethodBlock(lit) {
+				return false
+			}
+			members.Methods = append(members.Methods, lit)
+
+		case token.VALIDATE:
+			lit := &ast.FuncLiteral{Token: p.curToken}
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.parseMethodParameters(lit) {
+				return false
+			}
+			if len(lit.Parameters) != 1 {
+				p.errorAt(lit.Name.Token, "validator for %s needs exactly 1 parameter, got %d", lit.Name.Value, len(lit.Parameters))
+				return false
+			}
+			if !p.parseMethodBlock(lit) {
+				return false
+			}
+			members.Validators = append(members.Validators, lit)
+
+		case token.OVERLOAD:
+			lit := p.parseOverload()
+			if lit == nil {
+				return false
+			}
+			members.Overloads = append(members.Overloads, lit)
+
+		default:
+			p.errorAt(p.curToken, "unexpected %q in %s body", p.curToken.Literal, start.Literal)
+			return false
+		}
+	}
+}
+
+// parseIsExpression parses Left is Type.
+func (p *Parser) parseIsExpression(left ast.Expr) ast.Expr {
+	exp := &ast.IsExpression{Token: p.curToken, Left: left}
+	if !p.expectTypeName() {
+		return nil
+	}
+	exp.Type = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
+}
+
+// parseOverload parses overload name: params { body }, where name is a
+// method name or an operator such as + or [].
+func (p *Parser) parseOverload() *ast.FuncLiteral {
+	lit := &ast.FuncLiteral{Token: p.curToken}
+	p.nextToken()
+	nameTok := p.curToken
+	params, isOperator := operatorParameters[nameTok.Type]
+	switch {
+	case nameTok.Type == token.IDENT:
+	case nameTok.Type == token.LBRACK:
+		if !p.expectPeek(token.RBRACK) {
+			return nil
+		}
+		nameTok.Literal = "[]"
+	case !isOperator:
+		p.errorAt(nameTok, "cannot overload %q", nameTok.Literal)
+		return nil
+	}
+	lit.Name = &ast.Ident{Token: nameTok, Value: nameTok.Literal}
+
+	if !p.parseMethodBody(lit) {
+		return nil
+	}
+	if isOperator && len(lit.Parameters) != params {
+		p.errorAt(nameTok, "overload %s needs exactly %d parameter, got %d", lit.Name.Value, params, len(lit.Parameters))
+		return nil
+	}
 	return lit
 }
 
+// parseTryExpression parses try { ... } catch name { ... }, where the
+// name is optional.
+func (p *Parser) parseTryExpression() ast.Expr {
+	exp := &ast.TryExpression{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	exp.Body = p.parseBlockStatement()
+
+	if !p.expectPeek(token.CATCH) {
+		return nil
+	}
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		exp.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	exp.Catch = p.parseBlockStatement()
+	return exp
+}
+
+// parseErrorExpression parses error(message).
+func (p *Parser) parseErrorExpression() ast.Expr {
+	exp := &ast.ErrorExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Message = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return exp
+}
+
+// parseDeferStatement parses defer call(args).
+func (p *Parser) parseDeferStatement() ast.Stmt {
+	stmt := &ast.DeferStatement{Token: p.curToken}
+	// p.yields has an entry for each method being parsed
+	if len(p.yields) == 0 {
+		p.errorAt(p.curToken, "defer outside of a method")
+		return nil
+	}
+	p.nextToken()
+	exp := p.parseExpression(LOWEST)
+	call, ok := exp.(*ast.CallExpression)
+	if !ok {
+		if exp != nil {
+			p.errorAt(stmt.Token, "defer needs a method call, got %s", exp.String())
+		}
+		return nil
+	}
+	stmt.Call = call
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseYieldExpression parses yield value, and marks the method it is in
+// as a generator.
+func (p *Parser) parseYieldExpression() ast.Expr {
+	exp := &ast.YieldExpression{Token: p.curToken}
+	if len(p.yields) == 0 {
+		p.errorAt(p.curToken, "yield outside of a method")
+		return nil
+	}
+	p.yields[len(p.yields)-1] = true
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Stmt{}
@@ -448,23 +1420,68 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	return block
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Ident {
-	var idents []*ast.Ident
+// parseFunctionParameters parses name, name = default, ... into lit.
+// Once a parameter has a default, every later one must too. A
+// parameter can also be a destructuring pattern such as [a, b] or
+// {name}.
+func (p *Parser) parseFunctionParameters(lit *ast.FuncLiteral) bool {
 	if p.peekTokenIs(token.RBRACE) {
-		return nil
+		return true
 	}
-	p.nextToken()
-	ident := &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
-	idents = append(idents, ident)
 
-	for p.peekTokenIs(token.COMMA) {
+	seen := make(map[string]bool)
+	hasDefault := false
+	for {
 		p.nextToken()
+		start := p.curToken
+		target := p.parseTarget()
+		if target == nil {
+			return false
+		}
+		for _, name := range ast.TargetNames(target) {
+			if seen[name.Value] {
+				p.errorAt(name.Token, "duplicate parameter %s", name.Value)
+				return false
+			}
+			seen[name.Value] = true
+		}
+
+		ident, ok := target.(*ast.Ident)
+		var pattern ast.Target
+		if !ok {
+			ident = &ast.Ident{Token: start, Value: target.String()}
+			pattern = target
+		}
+		lit.Types = append(lit.Types, p.parseTypeAnnotation())
+
+		var def ast.Expr
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(LOWEST)
+			if def == nil {
+				return false
+			}
+			hasDefault = true
+		} else if hasDefault {
+			p.errorAt(ident.Token, "parameter %s needs a default because an earlier parameter has one", ident.Value)
+			return false
+		}
+		lit.Parameters = append(lit.Parameters, ident)
+		lit.Defaults = append(lit.Defaults, def)
+		lit.Patterns = append(lit.Patterns, pattern)
+
+		if !p.peekTokenIs(token.COMMA) {
+			return true
+		}
 		p.nextToken()
-		ident := &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
-		idents = append(idents, ident)
 	}
+}
 
-	return idents
+// errorAt records a parse error positioned at tok.
+func (p *Parser) errorAt(tok token.Token, format string, a ...interface{}) {
+	msg := fmt.Sprintf("line %v, col %v: %s", tok.Line, tok.Col, fmt.Sprintf(format, a...))
+	p.errors = append(p.errors, msg)
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -484,8 +1501,9 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	return false
 }
 
+// Errors returns the lexer's errors followed by the parser's own
 func (p *Parser) Errors() []string {
-	return p.errors
+	return append(append([]string{}, p.l.Errors()...), p.errors...)
 }
 
 func (p *Parser) peekError(expect token.TokenType, t token.Token) {
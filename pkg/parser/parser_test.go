@@ -164,6 +164,92 @@ func TestIntegerLiteralExpr(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpr(t *testing.T) {
+	input := "99999999999999999999"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStmt)
+	literal, ok := stmt.Expression.(*ast.IntLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntLiteral, got %T", stmt.Expression)
+	}
+
+	if literal.Big == nil || literal.Big.String() != input {
+		t.Errorf("literal.Big not %s, got %v", input, literal.Big)
+	}
+}
+
+func TestNumericLiteralForms(t *testing.T) {
+	intTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+	}
+
+	for _, tt := range intTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStmt)
+		literal, ok := stmt.Expression.(*ast.IntLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntLiteral, got %T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d, got %d", tt.expected, literal.Value)
+		}
+	}
+
+	floatTests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"1e3", 1000},
+		{"2.5e-1", 0.25},
+		{"1_000.5", 1000.5},
+	}
+
+	for _, tt := range floatTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStmt)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral, got %T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g, got %g", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestMalformedNumberErrors(t *testing.T) {
+	p := New(lexer.New("x = 0b12"))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+	}
+	expected := "line 1, col 8: invalid digit '2' in binary literal"
+	if errors[0] != expected {
+		t.Errorf("expected %q, got %q", expected, errors[0])
+	}
+}
+
 func TestBooleanLiteralExpr(t *testing.T) {
 	input := "true"
 	l := lexer.New(input)
@@ -202,6 +288,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5", "!", 5},
 		{"-15", "-", 15},
+		{"~15", "~", 15},
 		{"!true", "!", true},
 		{"!false", "!", false},
 	}
@@ -267,12 +354,20 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 - 5", 5, "-", 5},
 		{"5 * 5", 5, "*", 5},
 		{"5 / 5", 5, "/", 5},
+		{"5 % 5", 5, "%", 5},
+		{"5 ** 5", 5, "**", 5},
+		{"5 & 5", 5, "&", 5},
+		{"5 | 5", 5, "|", 5},
+		{"5 ^ 5", 5, "^", 5},
+		{"5 << 5", 5, "<<", 5},
+		{"5 >> 5", 5, ">>", 5},
 		{"5 > 5", 5, ">", 5},
 		{"5 < 5", 5, "<", 5},
 		{"5 == 5", 5, "==", 5},
 		{"5 != 5", 5, "!=", 5},
 		{"5 <= 5", 5, "<=", 5},
 		{"5 >= 5", 5, ">=", 5},
+		{"a in b", "a", "in", "b"},
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
@@ -306,6 +401,19 @@ func TestOperatorPriorityParsing(t *testing.T) {
 	}{
 		{"-a * b", "((-a) * b)"},
 		{"!-a", "(!(-a))"},
+		{"~a & b", "((~a) & b)"},
+		{"a + b % c", "(a + (b % c))"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"-a ** b", "(-(a ** b))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"a | b & c", "(a | (b & c))"},
+		{"0..n + 1", "(0..(n + 1))"},
+		{"x in 0..<10", "(x in (0..<10))"},
+		{"0..10 step 2 * 2", "(0..10 step (2 * 2))"},
+		{"a..b == c", "((a..b) == c)"},
+		{"a ^ b << c", "(a ^ (b << c))"},
+		{"a & b == c", "((a & b) == c)"},
+		{"a + b >> c", "(a + (b >> c))"},
 		{"a + b + c", "((a + b) + c)"},
 		{"a + b - c", "((a + b) - c)"},
 		{"a * b * c", "((a * b) * c)"},
@@ -320,6 +428,7 @@ func TestOperatorPriorityParsing(t *testing.T) {
 		{"false", "false"},
 		{"3 > 5 == false", "((3 > 5) == false)"},
 		{"3 < 5 == true", "((3 < 5) == true)"},
+		{"a + b in c == true", "(((a + b) in c) == true)"},
 		{"1 + (2 + 3) + 4", "((1 + (2 + 3)) + 4)"},
 		{"(5 + 5) * 2", "((5 + 5) * 2)"},
 		{"2 / (5 + 5)", "(2 / (5 + 5))"},
@@ -442,6 +551,352 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		value    string
+		expected string
+	}{
+		{"for x in 0..<3 { x }", "", "x", "for x in (0..<3) { x }"},
+		{"for i, v in items { v }", "i", "v", "for i, v in items { v }"},
+		{"for x in xs { x };", "", "x", "for x in xs { x }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement, got %d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] not *ast.ForStatement, got %T", program.Statements[0])
+		}
+		if tt.key == "" && stmt.Key != nil {
+			t.Errorf("stmt.Key not nil, got %s", stmt.Key)
+		}
+		if tt.key != "" && !testIdentifier(t, stmt.Key, tt.key) {
+			return
+		}
+		if !testIdentifier(t, stmt.Value.(*ast.Ident), tt.value) {
+			return
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { 1 -> \"one\", _ -> \"other\" }", "match x { 1 -> one, _ -> other }"},
+		{"match x {\n\t-1 -> a\n\t1..9 -> b,\n\tn int if n > 9 -> c\n}",
+			"match x { (-1) -> a, (1..9) -> b, n int if (n > 9) -> c }"},
+		{"match x { n null -> n, null -> 0 }", "match x { n null -> n, null -> 0 }"},
+		{"match p { [a, _, ...rest] -> a, {\"k\": [v]} -> v, _ string -> 0 }",
+			"match p { [a, _, ...rest] -> a, {k: [v]} -> v, _ string -> 0 }"},
+		{"y = match x { n -> n }", "y = match x { n -> n }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement, got %d", len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestMatchParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { n int 5 }", "line 1, col 17: expected ->, got INT"},
+		{"match x { [...rest, a] -> a }", "line 1, col 15: ...rest must be the last element of an array pattern"},
+		{"match x { [a, rest*] -> a }", "line 1, col 15: write the rest of an array pattern as ...rest"},
+		{"match x { ( -> 1 }", "line 1, col 11: unexpected \"(\" in match pattern"},
+		{"match x { 1 -> 2", "line 1, col 1: match is missing its closing }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestYieldParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		generator bool
+		expected  string
+	}{
+		{"meth { yield 1 }", true, "meth: { yield 1 }"},
+		{"meth: n { for i in 0..n { yield i * i } }", true, "meth: n{ for i in (0..n) { yield (i * i) } }"},
+		{"meth { 1 }", false, "meth: { 1 }"},
+		{"meth { meth { yield 1 } }", false, "meth: { meth: { yield 1 } }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStmt)
+		fn, ok := stmt.Expression.(*ast.FuncLiteral)
+		if !ok {
+			t.Fatalf("expected *ast.FuncLiteral, got %T", stmt.Expression)
+		}
+		if fn.IsGenerator != tt.generator {
+			t.Errorf("%s: IsGenerator = %t, want %t", tt.input, fn.IsGenerator, tt.generator)
+		}
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	p := New(lexer.New("yield 1"))
+	p.ParseProgram()
+	if errors := p.Errors(); len(errors) == 0 || errors[0] != "line 1, col 1: yield outside of a method" {
+		t.Errorf("expected yield outside of a method error, got %v", errors)
+	}
+}
+
+func TestTryParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() } catch err { g(err) }", "try { f() } catch err { g(err) }"},
+		{"x = try { f() } catch { 0 }", "x = try { f() } catch { 0 }"},
+		{"error(\"bad \" + name)", "error((bad  + name))"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, got)
+		}
+	}
+
+	p := New(lexer.New("try { f() } 1"))
+	p.ParseProgram()
+	if errors := p.Errors(); len(errors) == 0 || errors[0] != "line 1, col 13: expected CATCH, got INT" {
+		t.Errorf("expected missing catch error, got %v", errors)
+	}
+}
+
+func TestNullParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null", "null"},
+		{"a.b.c", "((a.b).c)"},
+		{"a?.b", "(a?.b)"},
+		{"a?.b.c", "((a?.b).c)"},
+		{"a?[0]", "(a?[0])"},
+		{"a?[1:]", "(a?[1:])"},
+		{"a.b?[\"c\"]?.d", "(((a.b)?[c])?.d)"},
+		{"a.f(1)", "(a.f)(1)"},
+		{"a ?? b", "(a ?? b)"},
+		{"a ?? b + c", "(a ?? (b + c))"},
+		{"a ?? b == c", "((a ?? b) == c)"},
+		{"a < b ?? c", "(a < (b ?? c))"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"-a.b", "(-(a.b))"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	p := New(lexer.New("a.1"))
+	p.ParseProgram()
+	if errors := p.Errors(); len(errors) == 0 || errors[0] != "line 1, col 3: expected IDENT, got INT" {
+		t.Errorf("expected member name error, got %v", errors)
+	}
+}
+
+func TestDeferParsing(t *testing.T) {
+	p := New(lexer.New("meth { defer close(f, force: true); 1 }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if got := program.String(); got != "meth: { defer close(f, force: true)1 }" {
+		t.Errorf("unexpected program %q", got)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"defer close(f)", "line 1, col 1: defer outside of a method"},
+		{"meth { defer x }", "line 1, col 8: defer needs a method call, got x"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestDescribeParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"describe Money: Amount {\n const Zero = 0\n Rate = 1\n meth show { Amount }\n}",
+			"describe Money: Amount { const Zero = 0; Rate = 1; meth show: { Amount } }",
+		},
+		{
+			"describe Vector: Items { overload []: i { Items[i] }\n overload len { len(Items) } }",
+			"describe Vector: Items { overload []: i{ (Items[i]) }; overload len: { len(Items) } }",
+		},
+		{"object Pounds: Money, Named { overload +: o { o } }", "object Pounds: Money, Named { overload +: o{ o } }"},
+		{"object Empty {}", "object Empty {}"},
+		{"describe D {}; object O: D {}; 1", "describe D {}object O: D {}1"},
+		{"describe Shape {\n meth area\n meth scale: by\n}", "describe Shape { meth area; meth scale: by }"},
+		{"describe Shape { meth area }", "describe Shape { meth area }"},
+		{"x is Shape == true", "((x is Shape) == true)"},
+		{"a + b is int", "((a + b) is int)"},
+		{"x is null", "(x is null)"},
+		{"describe D: A { validate A: a { a > 0 } }", "describe D: A { validate A: a{ (a > 0) } }"},
+		{"meth { Jet.Speed = 2 * x }", "meth: { Jet.Speed = (2 * x) }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"describe M { overload +: a, b { 1 } }", "line 1, col 23: overload + needs exactly 1 parameter, got 2"},
+		{"describe M { overload ! { 1 } }", `line 1, col 23: cannot overload "!"`},
+		{"describe M { meth { 1 } }", "line 1, col 14: a method in a describe needs a name"},
+		{"object M { 5 }", `line 1, col 12: unexpected "5" in object body`},
+		{"describe M: A, A {}", "line 1, col 16: duplicate parameter A"},
+		{"describe M { x = 1", "line 1, col 1: describe is missing its closing }"},
+		{"object M { meth area }", "line 1, col 22: expected {, got }"},
+		{"x is 5", "line 1, col 6: expected IDENT, got INT"},
+		{"describe D: A { validate A: a, b { 1 } }", "line 1, col 26: validator for A needs exactly 1 parameter, got 2"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestEnumParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum Colour { Red, Green, Blue }", "enum Colour { Red, Green, Blue }"},
+		{"enum Size { Small }; Size.Small", "enum Size { Small }(Size.Small)"},
+		{"enum Colour {\n\tRed,\n\tGreen\n\tBlue,\n}", "enum Colour { Red, Green, Blue }"},
+		{"match c { Colour.Red -> 1, _ -> 2 }", "match c { (Colour.Red) -> 1, _ -> 2 }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum Colour { Red, Red }", "line 1, col 20: duplicate member Red"},
+		{"enum Colour { Red Green }", `line 1, col 19: expected , or }, got "Green"`},
+		{"enum Colour { 1 }", `line 1, col 15: unexpected "1" in enum body`},
+		{"enum Colour {}", "line 1, col 6: enum Colour needs at least one member"},
+		{"enum Colour { Red,", "line 1, col 1: enum is missing its closing }"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"meth area: w int, h int -> int { w * h }", "meth area: w int, h int -> int { (w * h) }"},
+		{"meth: x, y float = 1.5 { x }", "meth: x, y float = 1.5{ x }"},
+		{"meth now -> int { 1 }", "meth now: -> int { 1 }"},
+		{"meth f: a null -> null { a }", "meth f: a null -> null { a }"},
+		{"meth: [a, b] array { a }", "meth: [a, b] array{ a }"},
+		{"describe Jet: Name string, TopSpeed int { Wheels int = 4 }", "describe Jet: Name string, TopSpeed int { Wheels int = 4 }"},
+		{"describe Shape { meth area -> float }", "describe Shape { meth area -> float }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	p := New(lexer.New("meth f: x -> 5 { x }"))
+	p.ParseProgram()
+	if errors := p.Errors(); len(errors) == 0 || errors[0] != "line 1, col 14: expected IDENT, got INT" {
+		t.Errorf("expected return type error, got %v", errors)
+	}
+}
+
 func TestFuncLiteralParsing(t *testing.T) {
 	input := `meth: x, y { x + y }`
 	l := lexer.New(input)
@@ -508,6 +963,168 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestDefaultsAndNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"meth connect: host, port = 8080 { host }", "meth connect: host, port = 8080{ host }"},
+		{"meth: a, b = a * 2 { b }", "meth: a, b = (a * 2){ b }"},
+		{"connect(\"x\", port: 1)", "connect(x, port: 1)"},
+		{"connect(host: \"x\", port: 1 + 1)", "connect(host: x, port: (1 + 1))"},
+		{"f({a: 1})", "f({a:1})"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"meth: a, a {}", "line 1, col 10: duplicate parameter a"},
+		{"meth: a = 1, b {}", "line 1, col 14: parameter b needs a default because an earlier parameter has one"},
+		{"f(a: 1, 2)", "line 1, col 9: positional argument follows named argument"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestSpreadParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(...args)", "f(...args)"},
+		{"f(1, ...rest, x: 2)", "f(1, ...rest, x: 2)"},
+		{"[...a, 1, ...b]", "[...a, 1, ...b]"},
+		{"[...f(x)]", "[...f(x)]"},
+		{`{...defaults, "port": 1}`, "{...defaults, port:1}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestComprehensionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[x * 2 for x in items]", "[(x * 2) for x in items]"},
+		{"[x for x in items if x > 0]", "[x for x in items if (x > 0)]"},
+		{"[i + v for i, v in [1, 2]]", "[(i + v) for i, v in [1, 2]]"},
+		{"[x for x in 1..10 step 2]", "[x for x in (1..10 step 2)]"},
+		{"{k: v for k, v in m}", "{k:v for k, v in m}"},
+		{"{str(x): x for x in xs if x}", "{str(x):x for x in xs if x}"},
+		{"[[y for y in x] for x in xs]", "[[y for y in x] for x in xs]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"[x for 1 in xs]", "line 1, col 8: expected IDENT, got INT"},
+		{"[x for x of xs]", "line 1, col 10: expected in, got IDENT"},
+		{"[x for x in xs, 1]", "line 1, col 15: expected ], got ,"},
+		{"{1: 2, k: v for k, v in m}", "line 1, col 13: expected ,, got FOR"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestDestructuringParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[a, b] = pair", "[a, b] = pair"},
+		{"[a, b, ...rest] = list", "[a, b, ...rest] = list"},
+		{"[a, [b, c]] = nested", "[a, [b, c]] = nested"},
+		{"{name, age} = person", "{name, age} = person"},
+		{`{"full name": n, ...rest} = person`, "{full name:n, ...rest} = person"},
+		{`{"pos": [x, y]} = point`, "{pos:[x, y]} = point"},
+		{"for [a, b] in pairs { a }", "for [a, b] in pairs { a }"},
+		{"for i, {name} in people { name }", "for i, {name} in people { name }"},
+		{"[a + b for [a, b] in pairs]", "[(a + b) for [a, b] in pairs]"},
+		{"meth: [a, b], {name} = {} { a }", "meth: [a, b], {name} = {}{ a }"},
+		{"{name, age}", "{name:name, age:age}"},
+		{"[1, 2]", "[1, 2]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"[a, 1] = x", "line 1, col 1: cannot assign to 1"},
+		{"[...rest, a] = x", "line 1, col 2: ...rest must be the last element of a destructuring pattern"},
+		{"{k: v} = x", "line 1, col 1: cannot use k as a key in a destructuring pattern"},
+		{"meth: [a, b], a {}", "line 1, col 15: duplicate parameter a"},
+		{"for 1 in x {}", "line 1, col 5: expected IDENT, got INT"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5)"
 	l := lexer.New(input)
@@ -678,6 +1295,46 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		low      interface{}
+		high     interface{}
+		expected string
+	}{
+		{"myArray[1:3]", 1, 3, "(myArray[1:3])"},
+		{"myArray[:2]", nil, 2, "(myArray[:2])"},
+		{"myArray[2:]", 2, nil, "(myArray[2:])"},
+		{"myArray[:]", nil, nil, "(myArray[:])"},
+		{"myArray[-1:]", nil, nil, "(myArray[(-1):])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, _ := program.Statements[0].(*ast.ExpressionStmt)
+		sliceExp, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("exp not ast.SliceExpression, got %T", stmt.Expression)
+		}
+		if sliceExp.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, sliceExp.String())
+		}
+		if !testIdentifier(t, sliceExp.Left, "myArray") {
+			return
+		}
+		if tt.low != nil {
+			testLiteralExpression(t, sliceExp.Low, tt.low)
+		}
+		if tt.high != nil {
+			testLiteralExpression(t, sliceExp.High, tt.high)
+		}
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 	l := lexer.New(input)
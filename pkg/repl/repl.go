@@ -7,6 +7,7 @@ import (
 	"github.com/alexjwhite-cb/jet/pkg/lexer"
 	"github.com/alexjwhite-cb/jet/pkg/object"
 	"github.com/alexjwhite-cb/jet/pkg/parser"
+	"github.com/alexjwhite-cb/jet/pkg/resolver"
 	"io"
 )
 
@@ -32,6 +33,9 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
+		// Names from earlier lines are unknown to the resolver, so its
+		// diagnostics are ignored and those names are looked up as usual
+		resolver.Resolve(program)
 		evaluated := evaluator.Eval(program, env)
 		//io.WriteString(out, program.String())
 		if evaluated != nil {
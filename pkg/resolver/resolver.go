@@ -0,0 +1,448 @@
+// Package resolver works out which variable each name in a program
+// refers to before it runs, following the evaluator's scope rules. It
+// reports names that refer to nothing, warns about variables that are
+// never used or that shadow another, and records where each variable
+// lives on its ast.Ident so the evaluator can go straight to it.
+package resolver
+
+import (
+	"fmt"
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/token"
+	"sort"
+)
+
+// builtins lists the evaluator's builtin methods, which every scope can
+// see.
+var builtins = map[string]bool{
+	"puts": true, "print": true, "len": true, "array": true, "next": true, "str": true,
+	"first": true, "tail": true, "append": true, "sort": true, "descriptors": true, "close": true,
+}
+
+type resolver struct {
+	errors   []string
+	warnings []string
+}
+
+// Resolve annotates the names in program with where their variables
+// live, and returns the errors and warnings it finds, each prefixed
+// with its line and column.
+func Resolve(program *ast.Program) (errors, warnings []string) {
+	r := &resolver{}
+	r.body(program.Statements, newScope(nil, false))
+	return r.errors, r.warnings
+}
+
+func (r *resolver) errorAt(tok token.Token, format string, a ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf("line %v, col %v: %s", tok.Line, tok.Col, fmt.Sprintf(format, a...)))
+}
+
+func (r *resolver) warnAt(tok token.Token, format string, a ...interface{}) {
+	r.warnings = append(r.warnings, fmt.Sprintf("line %v, col %v: %s", tok.Line, tok.Col, fmt.Sprintf(format, a...)))
+}
+
+// body resolves stmts as the whole of sc. Everything they declare is
+// declared first, so that methods can refer to names assigned after
+// them.
+func (r *resolver) body(stmts []ast.Stmt, sc *scope) {
+	r.hoist(stmts, sc)
+	for _, stmt := range stmts {
+		r.stmt(stmt, sc)
+	}
+	r.unused(sc)
+}
+
+// hoist declares the names stmts assign in sc, including those assigned
+// by if and try blocks, which share their enclosing scope.
+func (r *resolver) hoist(stmts []ast.Stmt, sc *scope) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.ValueStmt:
+			r.assign(s, sc)
+		case *ast.DescribeStatement:
+			r.define(s.Name, sc).members = descriptorMembers(s)
+		case *ast.ObjectStatement:
+			r.define(s.Name, sc)
+		case *ast.EnumStatement:
+			r.define(s.Name, sc)
+		case *ast.ExpressionStmt:
+			switch e := s.Expression.(type) {
+			case *ast.FuncLiteral:
+				if e.Name != nil {
+					r.define(e.Name, sc)
+				}
+			case *ast.IfExpression:
+				r.hoist(e.Consequence.Statements, sc)
+				if e.Alternative != nil {
+					r.hoist(e.Alternative.Statements, sc)
+				}
+			case *ast.TryExpression:
+				r.hoist(e.Body.Statements, sc)
+			}
+		}
+	}
+}
+
+// declare adds a variable called ident to sc, warning if it hides one
+// from an enclosing scope.
+func (r *resolver) declare(ident *ast.Ident, sc *scope) *variable {
+	if sc.outer != nil && ident.Value != "_" {
+		if prev, _, _ := sc.outer.lookup(ident.Value); prev != nil {
+			tok := prev.ident.Token
+			r.warnAt(ident.Token, "%s shadows the declaration at line %v, col %v", ident.Value, tok.Line, tok.Col)
+		}
+	}
+	return sc.add(ident)
+}
+
+// define declares ident in sc the way Environment.Set does, unless sc
+// already has it.
+func (r *resolver) define(ident *ast.Ident, sc *scope) *variable {
+	if v, ok := sc.names[ident.Value]; ok {
+		return v
+	}
+	return r.declare(ident, sc)
+}
+
+// assign declares the names a value statement assigns, unless they
+// update variables that already exist.
+func (r *resolver) assign(stmt *ast.ValueStmt, sc *scope) {
+	names := ast.TargetNames(stmt.Target)
+	if stmt.Name != nil {
+		names = []*ast.Ident{stmt.Name}
+	}
+	for _, name := range names {
+		if sc.assigned(name.Value) == nil {
+			r.declare(name, sc).local = sc.outer != nil
+		}
+	}
+}
+
+// unused warns about the variables sc's statements assign but never
+// read.
+func (r *resolver) unused(sc *scope) {
+	var unused []*variable
+	for _, v := range sc.names {
+		if v.local && !v.used && v.ident.Value != "_" {
+			unused = append(unused, v)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool { return unused[i].slot < unused[j].slot })
+	for _, v := range unused {
+		r.warnAt(v.ident.Token, "%s declared and not used", v.ident.Value)
+	}
+}
+
+// resolve records where the variable ident refers to lives, and reports
+// it if there is no such variable.
+func (r *resolver) resolve(ident *ast.Ident, sc *scope) *variable {
+	v, depth, exact := sc.lookup(ident.Value)
+	if v == nil {
+		if exact && !builtins[ident.Value] {
+			r.errorAt(ident.Token, "identifier not found: %s", ident.Value)
+		}
+		return nil
+	}
+	v.used = true
+	if exact {
+		ident.Depth = depth
+		ident.Slot = v.slot
+	}
+	return v
+}
+
+func (r *resolver) stmt(stmt ast.Stmt, sc *scope) {
+	switch s := stmt.(type) {
+	case *ast.ExpressionStmt:
+		r.expr(s.Expression, sc)
+
+	case *ast.ValueStmt:
+		r.expr(s.Value, sc)
+		r.assign(s, sc)
+
+	case *ast.PropertyStmt:
+		r.expr(s.Target.Left, sc)
+		r.expr(s.Value, sc)
+
+	case *ast.ReturnStatement:
+		r.expr(s.Value, sc)
+
+	case *ast.BlockStatement:
+		for _, stmt := range s.Statements {
+			r.stmt(stmt, sc)
+		}
+
+	case *ast.DeferStatement:
+		r.expr(s.Call, sc)
+
+	case *ast.ForStatement:
+		r.expr(s.Iterable, sc)
+		loop := newScope(sc, true)
+		if s.Key != nil {
+			r.declare(s.Key, loop)
+		}
+		for _, name := range ast.TargetNames(s.Value) {
+			r.declare(name, loop)
+		}
+		r.body(s.Body.Statements, loop)
+
+	case *ast.DescribeStatement:
+		r.describe(s, sc)
+
+	case *ast.ObjectStatement:
+		r.object(s, sc)
+
+	case *ast.EnumStatement:
+		r.define(s.Name, sc)
+	}
+}
+
+func (r *resolver) expr(e ast.Expr, sc *scope) {
+	switch n := e.(type) {
+	case *ast.Ident:
+		r.resolve(n, sc)
+
+	case *ast.PrefixExpression:
+		r.expr(n.Right, sc)
+
+	case *ast.InfixExpression:
+		r.expr(n.Left, sc)
+		r.expr(n.Right, sc)
+
+	case *ast.IsExpression:
+		r.expr(n.Left, sc)
+
+	case *ast.IndexExpression:
+		r.expr(n.Left, sc)
+		r.expr(n.Index, sc)
+
+	case *ast.SliceExpression:
+		r.expr(n.Left, sc)
+		r.expr(n.Low, sc)
+		r.expr(n.High, sc)
+
+	case *ast.MemberExpression:
+		r.expr(n.Left, sc)
+
+	case *ast.CallExpression:
+		r.expr(n.Function, sc)
+		for _, arg := range n.Args {
+			r.expr(arg, sc)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, el := range n.Elements {
+			r.expr(el, sc)
+		}
+
+	case *ast.HashMap:
+		for _, key := range n.Keys {
+			r.expr(key, sc)
+			r.expr(n.Pairs[key], sc)
+		}
+
+	case *ast.SpreadExpression:
+		r.expr(n.Value, sc)
+
+	case *ast.RangeExpression:
+		r.expr(n.Start, sc)
+		r.expr(n.End, sc)
+		r.expr(n.Step, sc)
+
+	case *ast.FuncLiteral:
+		if n.Name != nil {
+			r.define(n.Name, sc)
+		}
+		r.function(n, sc)
+
+	case *ast.IfExpression:
+		r.expr(n.Condition, sc)
+		r.stmt(n.Consequence, sc)
+		if n.Alternative != nil {
+			r.stmt(n.Alternative, sc)
+		}
+
+	case *ast.MatchExpression:
+		r.expr(n.Subject, sc)
+		for _, arm := range n.Arms {
+			armScope := newScope(sc, true)
+			r.pattern(arm.Pattern, armScope)
+			r.expr(arm.Guard, armScope)
+			r.expr(arm.Body, armScope)
+		}
+
+	case *ast.TryExpression:
+		r.stmt(n.Body, sc)
+		catch := newScope(sc, true)
+		if n.Name != nil {
+			r.declare(n.Name, catch)
+		}
+		r.body(n.Catch.Statements, catch)
+
+	case *ast.ErrorExpression:
+		r.expr(n.Message, sc)
+
+	case *ast.YieldExpression:
+		r.expr(n.Value, sc)
+
+	case *ast.ArrayComprehension:
+		clause := r.clause(n.Clause, sc)
+		r.expr(n.Element, clause)
+
+	case *ast.MapComprehension:
+		clause := r.clause(n.Clause, sc)
+		r.expr(n.Key, clause)
+		r.expr(n.Value, clause)
+	}
+}
+
+// function resolves a method's defaults and body in a scope of its own,
+// holding its parameters. Each default can refer to the parameters
+// before it.
+func (r *resolver) function(lit *ast.FuncLiteral, sc *scope) {
+	if lit.Body == nil {
+		return
+	}
+	fn := newScope(sc, false)
+	for i, param := range lit.Parameters {
+		if i < len(lit.Defaults) {
+			r.expr(lit.Defaults[i], fn)
+		}
+		if i < len(lit.Patterns) && lit.Patterns[i] != nil {
+			for _, name := range ast.TargetNames(lit.Patterns[i]) {
+				r.declare(name, fn)
+			}
+			continue
+		}
+		r.declare(param, fn)
+	}
+	r.body(lit.Body.Statements, fn)
+}
+
+// clause declares the names a comprehension's clause binds in a new
+// block scope, which the rest of the comprehension is resolved in.
+func (r *resolver) clause(clause *ast.ComprehensionClause, sc *scope) *scope {
+	r.expr(clause.Iterable, sc)
+	inner := newScope(sc, true)
+	if clause.Key != nil {
+		r.declare(clause.Key, inner)
+	}
+	for _, name := range ast.TargetNames(clause.Value) {
+		r.declare(name, inner)
+	}
+	r.expr(clause.Condition, inner)
+	return inner
+}
+
+// pattern declares the names a match arm's pattern binds.
+func (r *resolver) pattern(p ast.Pattern, sc *scope) {
+	switch p := p.(type) {
+	case *ast.BindingPattern:
+		r.declare(p.Name, sc)
+	case *ast.ValuePattern:
+		r.expr(p.Value, sc)
+	case *ast.TypePattern:
+		if p.Name.Value != "_" {
+			r.declare(p.Name, sc)
+		}
+	case *ast.ArrayPattern:
+		for _, el := range p.Elements {
+			r.pattern(el, sc)
+		}
+		if p.Rest != nil {
+			r.declare(p.Rest, sc)
+		}
+	case *ast.HashPattern:
+		for _, value := range p.Values {
+			r.pattern(value, sc)
+		}
+	}
+}
+
+// member is a name bound in each instance of a descriptor or object.
+type member struct {
+	ident    *ast.Ident
+	property bool
+}
+
+// descriptorMembers lists the names an instance gets from the
+// descriptor node declares.
+func descriptorMembers(node *ast.DescribeStatement) []member {
+	var members []member
+	for _, param := range node.Parameters {
+		members = append(members, member{ident: param, property: true})
+	}
+	return append(members, bodyMembers(&node.Members)...)
+}
+
+// bodyMembers lists the properties and methods of a descriptor or
+// object body, which are bound in each of its instances. Operators and
+// init are not.
+func bodyMembers(m *ast.Members) []member {
+	var members []member
+	for _, prop := range m.Properties {
+		members = append(members, member{ident: prop.Name, property: true})
+	}
+	for _, lits := range [][]*ast.FuncLiteral{m.Required, m.Methods} {
+		for _, lit := range lits {
+			if lit.Name.Value != "init" {
+				members = append(members, member{ident: lit.Name})
+			}
+		}
+	}
+	return members
+}
+
+// describe resolves a descriptor's property values where it is
+// declared, and its methods in the scope of an instance. That instance
+// belongs to an object the descriptor can't see, so only the
+// descriptor's own members are placed in it.
+func (r *resolver) describe(node *ast.DescribeStatement, sc *scope) {
+	v := r.define(node.Name, sc)
+	v.members = descriptorMembers(node)
+	for _, prop := range node.Properties {
+		r.expr(prop.Value, sc)
+	}
+
+	inst := newScope(sc, false)
+	inst.open = true
+	for _, m := range v.members {
+		inst.add(m.ident).property = m.property
+	}
+	inst.add(node.Name)
+	r.methods(&node.Members, inst)
+}
+
+// object resolves an object's property values where it is declared,
+// and its methods in the scope of an instance holding its members and
+// those of its descriptors.
+func (r *resolver) object(node *ast.ObjectStatement, sc *scope) {
+	r.define(node.Name, sc)
+	inst := newScope(sc, false)
+	for _, ident := range node.Descriptors {
+		d := r.resolve(ident, sc)
+		if d == nil || d.members == nil {
+			inst.open = true
+			continue
+		}
+		for _, m := range d.members {
+			inst.add(m.ident).property = m.property
+		}
+		inst.add(ident)
+	}
+	for _, prop := range node.Properties {
+		r.expr(prop.Value, sc)
+	}
+	for _, m := range bodyMembers(&node.Members) {
+		inst.add(m.ident).property = m.property
+	}
+	r.methods(&node.Members, inst)
+}
+
+func (r *resolver) methods(m *ast.Members, inst *scope) {
+	for _, lits := range [][]*ast.FuncLiteral{m.Methods, m.Overloads, m.Validators} {
+		for _, lit := range lits {
+			r.function(lit, inst)
+		}
+	}
+}
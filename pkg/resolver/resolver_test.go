@@ -0,0 +1,152 @@
+package resolver
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/lexer"
+	"github.com/alexjwhite-cb/jet/pkg/parser"
+	"testing"
+)
+
+func testResolve(t *testing.T, input string) (*ast.Program, []string, []string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	errors, warnings := Resolve(program)
+	return program, errors, warnings
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x = 1\nputs(x + 1)", nil},
+		{"meth f { g() }\nmeth g { 1 }", nil},
+		{"meth f: a, b = a { a + b }", nil},
+		{"for i, v in [1] { puts(i + v) }", nil},
+		{"try { error(\"no\") } catch err { err }", nil},
+		{"enum Colour { Red }\nColour.Red", nil},
+		{"describe D: A { meth f { A + B } }", nil},
+		{"object O: Nope { meth f { A } }", []string{"line 1, col 11: identifier not found: Nope"}},
+		{"describe D: A { B = 1 }\nobject O: D { meth f { A + B + D.B } }", nil},
+		{"describe D: A {}\nobject O: D { meth f { C } }", []string{"line 2, col 24: identifier not found: C"}},
+		{"fooo", []string{"line 1, col 1: identifier not found: fooo"}},
+		{"meth f { if false { puts(typo) } }", []string{"line 1, col 26: identifier not found: typo"}},
+		{"meth f: a = b, b = 1 { a }", []string{"line 1, col 13: identifier not found: b"}},
+		{"for x in 1..3 { inner = x }\ninner", []string{"line 2, col 1: identifier not found: inner"}},
+		{"match 3 { n -> n }\nn", []string{"line 2, col 1: identifier not found: n"}},
+		{"[y for y in 1..3]\ny", []string{"line 2, col 1: identifier not found: y"}},
+	}
+
+	for _, tt := range tests {
+		_, errors, _ := testResolve(t, tt.input)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong errors for %q: want %q, got %q", tt.input, tt.expected, errors)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if errors[i] != msg {
+				t.Errorf("wrong error for %q: want %q, got %q", tt.input, msg, errors[i])
+			}
+		}
+	}
+}
+
+func TestResolveWarnings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x = 1", nil},
+		{"meth f: a { b = a; b }", nil},
+		{"meth f { _ = 1 }", nil},
+		{"meth f { x = 1; y = 2; x }", []string{"line 1, col 17: y declared and not used"}},
+		{"for i in 1..3 { last = i }", []string{"line 1, col 17: last declared and not used"}},
+		{"x = 0\nfor i in 1..3 { x = i }", nil},
+		{"x = 1\nmeth f: x { x }", []string{"line 2, col 9: x shadows the declaration at line 1, col 1"}},
+		{"x = 1\nfor x in 1..3 { puts(x) }", []string{"line 2, col 5: x shadows the declaration at line 1, col 1"}},
+		{"n = 0\nmeth f { n = 1; n }", []string{"line 2, col 10: n shadows the declaration at line 1, col 1"}},
+		{"describe D: A { meth f: b { A + b } }\nobject O: D { meth g { A } }", nil},
+		{"object C { Count = 0\nmeth inc { Count = Count + 1 } }", nil},
+		{"object C { meth inc { Count = 1 } }", []string{"line 1, col 23: Count declared and not used"}},
+	}
+
+	for _, tt := range tests {
+		_, _, warnings := testResolve(t, tt.input)
+		if len(warnings) != len(tt.expected) {
+			t.Errorf("wrong warnings for %q: want %q, got %q", tt.input, tt.expected, warnings)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if warnings[i] != msg {
+				t.Errorf("wrong warning for %q: want %q, got %q", tt.input, msg, warnings[i])
+			}
+		}
+	}
+}
+
+func TestResolveDepths(t *testing.T) {
+	program, _, _ := testResolve(t, `a = 1
+b = 2
+meth f: x {
+	for i in 1..x {
+		puts(i + x + b)
+	}
+}`)
+
+	var idents []*ast.Ident
+	var collect func(node ast.Node)
+	collect = func(node ast.Node) {
+		switch n := node.(type) {
+		case *ast.ExpressionStmt:
+			collect(n.Expression)
+		case *ast.FuncLiteral:
+			collect(n.Body)
+		case *ast.BlockStatement:
+			for _, stmt := range n.Statements {
+				collect(stmt)
+			}
+		case *ast.ForStatement:
+			collect(n.Iterable)
+			collect(n.Body)
+		case *ast.RangeExpression:
+			collect(n.End)
+		case *ast.CallExpression:
+			collect(n.Function)
+			for _, arg := range n.Args {
+				collect(arg)
+			}
+		case *ast.InfixExpression:
+			collect(n.Left)
+			collect(n.Right)
+		case *ast.Ident:
+			idents = append(idents, n)
+		}
+	}
+	collect(program.Statements[2])
+
+	expected := []struct {
+		name  string
+		depth int
+		slot  int
+	}{
+		{"x", 0, 0},
+		{"puts", 0, 0},
+		{"i", 0, 0},
+		{"x", 1, 0},
+		{"b", 2, 1},
+	}
+	if len(idents) != len(expected) {
+		t.Fatalf("wrong identifiers: want %d, got %d", len(expected), len(idents))
+	}
+	for i, tt := range expected {
+		ident := idents[i]
+		if ident.Value != tt.name || ident.Depth != tt.depth || ident.Slot != tt.slot {
+			t.Errorf("identifier %d: want %s at %d/%d, got %s at %d/%d",
+				i, tt.name, tt.depth, tt.slot, ident.Value, ident.Depth, ident.Slot)
+		}
+	}
+}
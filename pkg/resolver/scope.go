@@ -0,0 +1,85 @@
+package resolver
+
+import "github.com/alexjwhite-cb/jet/pkg/ast"
+
+// scope mirrors one evaluator environment: the file, an instance of a
+// descriptor or object, a method call, or a block such as a loop body,
+// match arm or catch.
+type scope struct {
+	names map[string]*variable
+	outer *scope
+	// block marks a scope like object.NewBlockEnvironment, whose
+	// assignments update variables in enclosing blocks and the method
+	block bool
+	// open marks an instance that may have members the resolver can't
+	// see, such as the instance a descriptor's methods run in
+	open bool
+}
+
+type variable struct {
+	ident *ast.Ident
+	// slot is the variable's position among its scope's declarations,
+	// which is where the evaluator stores it when it runs in that order
+	slot int
+	used bool
+	// local is set on variables that a method or block assigns, which
+	// are reported if they're never used
+	local bool
+	// members is set on a descriptor, and holds the names it declares
+	members []member
+	// property is set on the properties and constants of an instance,
+	// which its methods update by assigning to them
+	property bool
+}
+
+func newScope(outer *scope, block bool) *scope {
+	return &scope{names: make(map[string]*variable), outer: outer, block: block}
+}
+
+// add declares ident in s, unless s already has it.
+func (s *scope) add(ident *ast.Ident) *variable {
+	if v, ok := s.names[ident.Value]; ok {
+		return v
+	}
+	v := &variable{ident: ident, slot: len(s.names)}
+	s.names[ident.Value] = v
+	return v
+}
+
+// lookup finds the variable name refers to, and how many scopes out it
+// is. exact is false when the search passed an open scope, which might
+// hold a variable of the same name.
+func (s *scope) lookup(name string) (v *variable, depth int, exact bool) {
+	exact = true
+	for sc := s; sc != nil; sc = sc.outer {
+		if v, ok := sc.names[name]; ok {
+			return v, depth, exact
+		}
+		if sc.open {
+			exact = false
+		}
+		depth++
+	}
+	return nil, 0, exact
+}
+
+// assigned finds the variable that assigning to name in s would update,
+// searching out through block scopes as far as the enclosing method like
+// Environment.Assign, and then the properties of the instance the method
+// belongs to.
+func (s *scope) assigned(name string) *variable {
+	for sc := s; sc != nil; sc = sc.outer {
+		if v, ok := sc.names[name]; ok {
+			return v
+		}
+		if !sc.block {
+			if inst := sc.outer; inst != nil {
+				if v, ok := inst.names[name]; ok && v.property {
+					return v
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ const (
 	//	Identifiers
 	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
 	LBRACE = "{"
@@ -27,6 +28,8 @@ const (
 	//	Operators
 	DIVIDE      = "/"
 	MULTIPLY    = "*"
+	MODULO      = "%"
+	POWER       = "**"
 	PLUS        = "+"
 	INCREMENT   = "++"
 	MINUS       = "-"
@@ -39,12 +42,24 @@ const (
 	PASSTHROUGH = "->"
 	NOT         = "!"
 	QUESTION    = "?"
+	OPTDOT      = "?."
+	OPTINDEX    = "?["
+	COALESCE    = "??"
 	LESSTHAN    = "<"
 	LESSOREQUAL = "<="
 	MORETHAN    = ">"
 	MOREOREQUAL = ">="
 	AND         = "&&"
 	OR          = "||"
+	BITAND      = "&"
+	BITOR       = "|"
+	BITXOR      = "^"
+	BITNOT      = "~"
+	SHIFTLEFT   = "<<"
+	SHIFTRIGHT  = ">>"
+	RANGE       = ".."
+	RANGEEXCL   = "..<"
+	ELLIPSIS    = "..."
 	NEWLINE     = "\n"
 
 	//	Keywords
@@ -55,29 +70,51 @@ const (
 	DESCRIBE = "DESCRIBE"
 	OBJECT   = "OBJECT"
 	OVERLOAD = "OVERLOAD"
+	ENUM     = "ENUM"
+	VALIDATE = "VALIDATE"
+	CONST    = "CONST"
 	IN       = "in"
+	IS       = "is"
+	MATCH    = "MATCH"
+	YIELD    = "YIELD"
+	DEFER    = "DEFER"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
 	ERROR    = "error"
 	TRUE     = "true"
 	FALSE    = "false"
+	NULL     = "null"
 )
 
 var keywords = map[string]TokenType{
 	"meth":     METHOD,
 	"for":      FOR,
+	"match":    MATCH,
+	"yield":    YIELD,
+	"defer":    DEFER,
+	"try":      TRY,
+	"catch":    CATCH,
 	"if":       IF,
 	"else":     ELSE,
 	"describe": DESCRIBE,
 	"object":   OBJECT,
 	"overload": OVERLOAD,
+	"enum":     ENUM,
+	"validate": VALIDATE,
+	"const":    CONST,
 	"in":       IN,
+	"is":       IS,
 	"error":    ERROR,
 	"true":     TRUE,
 	"false":    FALSE,
+	"null":     NULL,
 }
 
 var operators = map[string]TokenType{
 	"*":  MULTIPLY,
+	"**": POWER,
 	"/":  DIVIDE,
+	"%":  MODULO,
 	"+":  PLUS,
 	"++": INCREMENT,
 	"-":  MINUS,
@@ -95,6 +132,12 @@ var operators = map[string]TokenType{
 	">=": MOREOREQUAL,
 	"&&": AND,
 	"||": OR,
+	"&":  BITAND,
+	"|":  BITOR,
+	"^":  BITXOR,
+	"~":  BITNOT,
+	"<<": SHIFTLEFT,
+	">>": SHIFTRIGHT,
 }
 
 var newline = map[string]TokenType{
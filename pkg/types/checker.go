@@ -0,0 +1,559 @@
+package types
+
+import (
+	"fmt"
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/token"
+)
+
+type checker struct {
+	errors      []string
+	warnings    []string
+	objects     map[string]*object
+	descriptors map[string]*descriptor
+	enums       map[string]*enum
+	// fn is the method whose body is being checked, or nil at the top
+	// level
+	fn *signature
+	// tries counts the try bodies around the code being checked. A
+	// fault there can be caught, so it is only a warning
+	tries int
+}
+
+// Check returns the type errors in program that can be found without
+// running it, and warnings for those that a try would catch, each
+// prefixed with its line and column.
+func Check(program *ast.Program) (errors, warnings []string) {
+	c := &checker{
+		objects:     make(map[string]*object),
+		descriptors: make(map[string]*descriptor),
+		enums:       make(map[string]*enum),
+	}
+	sc := newScope(nil)
+	c.hoist(program.Statements, sc, false)
+	c.statements(program.Statements, sc)
+	return c.errors, c.warnings
+}
+
+func (c *checker) errorAt(tok token.Token, format string, a ...interface{}) {
+	msg := fmt.Sprintf("line %v, col %v: %s", tok.Line, tok.Col, fmt.Sprintf(format, a...))
+	if c.tries > 0 {
+		c.warnings = append(c.warnings, msg)
+		return
+	}
+	c.errors = append(c.errors, msg)
+}
+
+// hoist declares the names that stmts give values to before they are
+// checked, so that methods can refer to names declared after them. The
+// names assigned by a conditional block, such as an if's branches, are
+// declared with nothing known about them.
+func (c *checker) hoist(stmts []ast.Stmt, sc *scope, conditional bool) {
+	define := func(name string, sym *symbol) {
+		if conditional {
+			sc.declare(name, &symbol{})
+		} else {
+			sc.names[name] = sym
+		}
+	}
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.DescribeStatement:
+			define(s.Name.Value, &symbol{descriptor: c.newDescriptor(s), fixed: true})
+		case *ast.ObjectStatement:
+			define(s.Name.Value, &symbol{object: c.newObject(s), fixed: true})
+		case *ast.EnumStatement:
+			define(s.Name.Value, &symbol{enum: c.newEnum(s), fixed: true})
+		case *ast.ValueStmt:
+			if s.Name != nil {
+				sc.declare(s.Name.Value, &symbol{})
+			}
+			for _, name := range ast.TargetNames(s.Target) {
+				sc.declare(name.Value, &symbol{})
+			}
+		case *ast.ExpressionStmt:
+			switch e := s.Expression.(type) {
+			case *ast.FuncLiteral:
+				if e.Name != nil {
+					define(e.Name.Value, &symbol{typ: Method, sig: newSignature(e), fixed: true})
+				}
+			case *ast.IfExpression:
+				c.hoist(e.Consequence.Statements, sc, true)
+				if e.Alternative != nil {
+					c.hoist(e.Alternative.Statements, sc, true)
+				}
+			case *ast.TryExpression:
+				c.hoist(e.Body.Statements, sc, true)
+			}
+		}
+	}
+}
+
+func newSignature(lit *ast.FuncLiteral) *signature {
+	sig := &signature{name: "method", returns: typeOf(lit.ReturnType), generator: lit.IsGenerator}
+	if lit.Name != nil {
+		sig.name = lit.Name.Value
+	}
+	for i, p := range lit.Parameters {
+		param := param{name: p.Value}
+		if i < len(lit.Types) {
+			param.typ = typeOf(lit.Types[i])
+		}
+		if i < len(lit.Defaults) && lit.Defaults[i] != nil {
+			param.optional = true
+		}
+		sig.params = append(sig.params, param)
+	}
+	return sig
+}
+
+// typeOf is the type that a type annotation names.
+func typeOf(ident *ast.Ident) Type {
+	if ident == nil {
+		return Dynamic
+	}
+	if t, ok := builtinTypes[ident.Value]; ok {
+		return t
+	}
+	return Type(ident.Value)
+}
+
+// annotation reports a type annotation that names no type.
+func (c *checker) annotation(ident *ast.Ident) {
+	if ident == nil {
+		return
+	}
+	if !c.defined(typeOf(ident)) {
+		c.errorAt(ident.Token, "unknown type: %s", ident.Value)
+	}
+}
+
+// defined reports whether t is a builtin type or one the program
+// declares.
+func (c *checker) defined(t Type) bool {
+	for _, builtin := range builtinTypes {
+		if t == builtin {
+			return true
+		}
+	}
+	name := string(t)
+	return c.objects[name] != nil || c.descriptors[name] != nil || c.enums[name] != nil
+}
+
+// known reports whether values of type t are builtin values or enum
+// members, whose operators can't be overloaded.
+func (c *checker) known(t Type) bool {
+	return primitive(t) || c.enums[string(t)] != nil
+}
+
+// assignable reports whether a value of type got can be given where
+// want is expected.
+func (c *checker) assignable(want, got Type) bool {
+	if want == Dynamic || got == Dynamic || want == got || !c.defined(want) {
+		return true
+	}
+	if c.descriptors[string(want)] != nil {
+		if o := c.objects[string(got)]; o != nil {
+			return o.describedBy(string(want))
+		}
+		// got may be an object described by both descriptors
+		return c.descriptors[string(got)] != nil
+	}
+	if c.descriptors[string(got)] != nil {
+		return c.objects[string(want)] != nil
+	}
+	return false
+}
+
+func (c *checker) newDescriptor(node *ast.DescribeStatement) *descriptor {
+	d := &descriptor{
+		name:      node.Name.Value,
+		props:     make(map[string]Type),
+		constants: make(map[string]Type),
+		methods:   make(map[string]*signature),
+		required:  make(map[string]*signature),
+	}
+	for i, p := range node.Parameters {
+		param := param{name: p.Value}
+		if i < len(node.Types) {
+			param.typ = typeOf(node.Types[i])
+		}
+		d.params = append(d.params, param)
+		d.props[p.Value] = param.typ
+	}
+	for _, prop := range node.Properties {
+		if prop.Const {
+			d.constants[prop.Name.Value] = literalType(prop.Value)
+		} else {
+			d.props[prop.Name.Value] = typeOf(prop.Type)
+		}
+	}
+	for _, lit := range node.Required {
+		d.required[lit.Name.Value] = newSignature(lit)
+	}
+	for _, lit := range node.Methods {
+		if lit.Name.Value != "init" {
+			d.methods[lit.Name.Value] = newSignature(lit)
+		}
+	}
+	c.descriptors[d.name] = d
+	return d
+}
+
+func (c *checker) newObject(node *ast.ObjectStatement) *object {
+	o := &object{
+		name:      node.Name.Value,
+		props:     make(map[string]Type),
+		constants: make(map[string]Type),
+		methods:   make(map[string]*signature),
+	}
+	for _, ident := range node.Descriptors {
+		d := c.descriptors[ident.Value]
+		if d == nil {
+			continue
+		}
+		o.descriptors = append(o.descriptors, d)
+		o.params = append(o.params, d.params...)
+		for name, t := range d.props {
+			o.props[name] = t
+		}
+		for name, t := range d.constants {
+			o.constants[name] = t
+		}
+		for name, sig := range d.methods {
+			o.methods[name] = sig
+		}
+	}
+	for _, prop := range node.Properties {
+		if prop.Const {
+			o.constants[prop.Name.Value] = literalType(prop.Value)
+		} else {
+			o.props[prop.Name.Value] = typeOf(prop.Type)
+		}
+	}
+	for _, lit := range node.Methods {
+		if lit.Name.Value != "init" {
+			o.methods[lit.Name.Value] = newSignature(lit)
+		}
+	}
+	for _, lit := range node.Overloads {
+		if !overloadable[lit.Name.Value] {
+			o.methods[lit.Name.Value] = newSignature(lit)
+		}
+	}
+	c.objects[o.name] = o
+	return o
+}
+
+// overloadable lists the overloads that define an operator rather than
+// a method.
+var overloadable = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "**": true,
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"[]": true, "len": true, "iter": true,
+}
+
+func (c *checker) newEnum(node *ast.EnumStatement) *enum {
+	e := &enum{name: node.Name.Value, members: make(map[string]bool)}
+	for _, member := range node.Members {
+		e.members[member.Value] = true
+	}
+	c.enums[e.name] = e
+	return e
+}
+
+// literalType is the type of a literal, and dynamic for anything else.
+func literalType(e ast.Expr) Type {
+	switch e.(type) {
+	case *ast.IntLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Null:
+		return Null
+	case *ast.ArrayLiteral, *ast.ArrayComprehension:
+		return Array
+	case *ast.HashMap, *ast.MapComprehension:
+		return Map
+	}
+	return Dynamic
+}
+
+// statements checks stmts in order, and returns the type of the last
+// one's value when it is an expression.
+func (c *checker) statements(stmts []ast.Stmt, sc *scope) Type {
+	result := Dynamic
+	for _, stmt := range stmts {
+		result = c.statement(stmt, sc)
+	}
+	return result
+}
+
+func (c *checker) statement(stmt ast.Stmt, sc *scope) Type {
+	switch s := stmt.(type) {
+	case *ast.ExpressionStmt:
+		return c.expr(s.Expression, sc)
+
+	case *ast.ValueStmt:
+		t := c.expr(s.Value, sc)
+		if s.Target != nil {
+			for _, name := range ast.TargetNames(s.Target) {
+				sc.assign(name.Value, &symbol{})
+			}
+			break
+		}
+		sc.assign(s.Name.Value, c.valueSymbol(s.Value, t, sc))
+
+	case *ast.PropertyStmt:
+		c.property(s, sc)
+
+	case *ast.ReturnStatement:
+		c.result(c.expr(s.Value, sc), s.Token)
+
+	case *ast.BlockStatement:
+		c.statements(s.Statements, sc)
+
+	case *ast.ForStatement:
+		it := c.expr(s.Iterable, sc)
+		body := newBlockScope(sc)
+		if s.Key != nil {
+			body.names[s.Key.Value] = &symbol{}
+		}
+		for _, name := range ast.TargetNames(s.Value) {
+			body.names[name.Value] = &symbol{}
+		}
+		if ident, ok := s.Value.(*ast.Ident); ok && it == Range {
+			body.names[ident.Value].typ = Int
+		}
+		c.hoist(s.Body.Statements, body, false)
+		c.statements(s.Body.Statements, body)
+
+	case *ast.DeferStatement:
+		c.expr(s.Call, sc)
+
+	case *ast.DescribeStatement:
+		c.describe(s, sc)
+
+	case *ast.ObjectStatement:
+		c.object(s, sc)
+
+	case *ast.EnumStatement:
+		e := c.enums[s.Name.Value]
+		if e == nil {
+			e = c.newEnum(s)
+		}
+		sc.names[e.name] = &symbol{enum: e, fixed: true}
+	}
+	return Dynamic
+}
+
+// valueSymbol is the symbol for a name assigned value, whose type is t.
+// Assigning a method or another name carries over what is known about
+// it.
+func (c *checker) valueSymbol(value ast.Expr, t Type, sc *scope) *symbol {
+	switch v := value.(type) {
+	case *ast.FuncLiteral:
+		return &symbol{typ: Method, sig: newSignature(v)}
+	case *ast.Ident:
+		if sym, ok := sc.lookup(v.Value); ok {
+			copied := *sym
+			copied.fixed = false
+			return &copied
+		}
+	}
+	return &symbol{typ: t}
+}
+
+// result checks a value that the method being checked returns against
+// its return type.
+func (c *checker) result(t Type, tok token.Token) {
+	if c.fn == nil || c.fn.generator || c.assignable(c.fn.returns, t) {
+		return
+	}
+	c.errorAt(tok, "return value of %s: want %s, got %s", c.fn.name, c.fn.returns, t)
+}
+
+// function checks a method's annotations, default values and body.
+func (c *checker) function(lit *ast.FuncLiteral, sc *scope) {
+	for _, t := range lit.Types {
+		c.annotation(t)
+	}
+	c.annotation(lit.ReturnType)
+	if lit.Body == nil {
+		return
+	}
+
+	sig := newSignature(lit)
+	if sig.generator && !c.assignable(sig.returns, Generator) {
+		c.errorAt(lit.ReturnType.Token, "return value of %s: want %s, got %s", sig.name, sig.returns, Generator)
+	}
+	body := newScope(sc)
+	for i, p := range lit.Parameters {
+		if i < len(lit.Defaults) && lit.Defaults[i] != nil {
+			if t := c.expr(lit.Defaults[i], body); !c.assignable(sig.params[i].typ, t) {
+				c.errorAt(p.Token, "argument %d to %s: want %s, got %s", i+1, sig.name, sig.params[i].typ, t)
+			}
+		}
+		if i < len(lit.Patterns) && lit.Patterns[i] != nil {
+			for _, name := range ast.TargetNames(lit.Patterns[i]) {
+				body.names[name.Value] = &symbol{}
+			}
+			continue
+		}
+		body.names[p.Value] = &symbol{typ: sig.params[i].typ}
+	}
+
+	// The body can be called from outside any try it is declared in
+	outer, tries := c.fn, c.tries
+	c.fn, c.tries = sig, 0
+	defer func() { c.fn, c.tries = outer, tries }()
+	c.hoist(lit.Body.Statements, body, false)
+	t := c.statements(lit.Body.Statements, body)
+	if n := len(lit.Body.Statements); n == 0 {
+		c.result(Null, lit.Body.Token)
+	} else if last, ok := lit.Body.Statements[n-1].(*ast.ExpressionStmt); ok {
+		c.result(t, last.Token)
+	}
+}
+
+func (c *checker) describe(node *ast.DescribeStatement, sc *scope) {
+	d := c.descriptors[node.Name.Value]
+	if d == nil {
+		d = c.newDescriptor(node)
+	}
+	for _, t := range node.Types {
+		c.annotation(t)
+	}
+	c.properties(node.Properties, d.name, sc)
+
+	// A descriptor's methods run in the scope of whichever object it
+	// describes, which may have members the descriptor doesn't know
+	members := c.members(sc, d.props, d.constants, d.required, []*descriptor{d})
+	for name, sig := range d.methods {
+		members.names[name] = &symbol{typ: Method, sig: sig, fixed: true}
+	}
+	members.open = true
+	c.methods(&node.Members, members)
+	sc.names[d.name] = &symbol{descriptor: d, fixed: true}
+}
+
+func (c *checker) object(node *ast.ObjectStatement, sc *scope) {
+	o := c.objects[node.Name.Value]
+	if o == nil {
+		o = c.newObject(node)
+	}
+	for _, ident := range node.Descriptors {
+		if c.descriptors[ident.Value] == nil {
+			c.errorAt(ident.Token, "%s is not a descriptor", ident.Value)
+		}
+	}
+	c.properties(node.Properties, o.name, sc)
+
+	members := c.members(sc, o.props, o.constants, o.methods, o.descriptors)
+	c.methods(&node.Members, members)
+	sc.names[o.name] = &symbol{object: o, fixed: true}
+}
+
+// properties checks the values properties of owner are declared with
+// against their annotations.
+func (c *checker) properties(props []*ast.Property, owner string, sc *scope) {
+	for _, prop := range props {
+		c.annotation(prop.Type)
+		t := c.expr(prop.Value, sc)
+		if want := typeOf(prop.Type); !c.assignable(want, t) {
+			c.errorAt(prop.Name.Token, "property %s of %s: want %s, got %s", prop.Name.Value, owner, want, t)
+		}
+	}
+}
+
+// members builds the scope an instance's methods run in, which holds
+// its members and a view of each of its descriptors.
+func (c *checker) members(outer *scope, props, constants map[string]Type, methods map[string]*signature, views []*descriptor) *scope {
+	sc := newScope(outer)
+	for name, t := range props {
+		// Annotated properties can only be assigned values of their
+		// type, so they keep it
+		sc.names[name] = &symbol{typ: t, fixed: t != Dynamic}
+	}
+	for name, t := range constants {
+		sc.names[name] = &symbol{typ: t, fixed: true}
+	}
+	for name, sig := range methods {
+		sc.names[name] = &symbol{typ: Method, sig: sig, fixed: true}
+	}
+	for _, d := range views {
+		sc.names[d.name] = &symbol{descriptor: d, view: true, fixed: true}
+	}
+	return sc
+}
+
+func (c *checker) methods(m *ast.Members, sc *scope) {
+	for _, lit := range m.Required {
+		c.function(lit, sc)
+	}
+	for _, lits := range [][]*ast.FuncLiteral{m.Methods, m.Overloads, m.Validators} {
+		for _, lit := range lits {
+			c.function(lit, sc)
+		}
+	}
+}
+
+// property checks an assignment to a property against what is declared
+// about it.
+func (c *checker) property(node *ast.PropertyStmt, sc *scope) {
+	name := node.Target.Name.Value
+	var owner Type
+	if sym := c.symbolOf(node.Target.Left, sc); sym != nil && sym.view {
+		d := sym.descriptor
+		if _, ok := d.constants[name]; ok {
+			c.errorAt(node.Token, "cannot assign to constant %s", name)
+			return
+		}
+		if _, ok := d.props[name]; !ok {
+			c.errorAt(node.Token, "%s does not describe property %s", d.name, name)
+			return
+		}
+		owner = Type(d.name)
+	} else {
+		owner = c.expr(node.Target.Left, sc)
+	}
+	value := c.expr(node.Value, sc)
+
+	var want Type
+	switch {
+	case c.objects[string(owner)] != nil:
+		o := c.objects[string(owner)]
+		if _, ok := o.constants[name]; ok {
+			c.errorAt(node.Token, "cannot assign to constant %s", name)
+			return
+		}
+		t, ok := o.props[name]
+		if !ok {
+			c.errorAt(node.Token, "%s has no property %s", o.name, name)
+			return
+		}
+		want = t
+	case c.descriptors[string(owner)] != nil:
+		want = c.descriptors[string(owner)].props[name]
+	case c.known(owner):
+		c.errorAt(node.Token, "cannot set %s of %s", name, owner)
+		return
+	}
+	if !c.assignable(want, value) {
+		c.errorAt(node.Token, "property %s of %s: want %s, got %s", name, owner, want, value)
+	}
+}
+
+// symbolOf returns the symbol e refers to when it is a name.
+func (c *checker) symbolOf(e ast.Expr, sc *scope) *symbol {
+	ident, ok := e.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	sym, _ := sc.lookup(ident.Value)
+	return sym
+}
@@ -0,0 +1,513 @@
+package types
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/ast"
+	"github.com/alexjwhite-cb/jet/pkg/token"
+)
+
+// expr checks e and returns the type of its value.
+func (c *checker) expr(e ast.Expr, sc *scope) Type {
+	switch n := e.(type) {
+	case *ast.IntLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean, *ast.Null:
+		return literalType(n)
+
+	case *ast.Ident:
+		if sym, ok := sc.lookup(n.Value); ok {
+			return sym.typ
+		}
+		if _, ok := builtins[n.Value]; ok {
+			return Method
+		}
+		return Dynamic
+
+	case *ast.ArrayLiteral:
+		for _, el := range n.Elements {
+			c.expr(el, sc)
+		}
+		return Array
+
+	case *ast.HashMap:
+		for _, key := range n.Keys {
+			c.expr(key, sc)
+			if value := n.Pairs[key]; value != nil {
+				c.expr(value, sc)
+			}
+		}
+		return Map
+
+	case *ast.SpreadExpression:
+		c.expr(n.Value, sc)
+		return Dynamic
+
+	case *ast.PrefixExpression:
+		return c.prefix(n.Operator, c.expr(n.Right, sc), n.Token)
+
+	case *ast.InfixExpression:
+		left := c.expr(n.Left, sc)
+		right := c.expr(n.Right, sc)
+		switch n.Operator {
+		case "??":
+			if left == Null {
+				return right
+			}
+			return left
+		case "in":
+			return c.in(left, right, n.Token)
+		}
+		return c.infix(n.Operator, left, right, n.Token)
+
+	case *ast.IsExpression:
+		c.expr(n.Left, sc)
+		c.annotation(n.Type)
+		return Bool
+
+	case *ast.IndexExpression:
+		return c.index(n, sc)
+
+	case *ast.SliceExpression:
+		return c.slice(n, sc)
+
+	case *ast.MemberExpression:
+		t, _ := c.member(n, sc, false)
+		return t
+
+	case *ast.CallExpression:
+		return c.call(n, sc)
+
+	case *ast.FuncLiteral:
+		c.function(n, sc)
+		if n.Name != nil {
+			sc.names[n.Name.Value] = &symbol{typ: Method, sig: newSignature(n), fixed: true}
+		}
+		return Method
+
+	case *ast.IfExpression:
+		c.expr(n.Condition, sc)
+		c.statements(n.Consequence.Statements, newBlockScope(sc))
+		if n.Alternative != nil {
+			c.statements(n.Alternative.Statements, newBlockScope(sc))
+		}
+		return Dynamic
+
+	case *ast.MatchExpression:
+		c.expr(n.Subject, sc)
+		for _, arm := range n.Arms {
+			armScope := newBlockScope(sc)
+			c.pattern(arm.Pattern, armScope)
+			if arm.Guard != nil {
+				c.expr(arm.Guard, armScope)
+			}
+			c.expr(arm.Body, armScope)
+		}
+		return Dynamic
+
+	case *ast.TryExpression:
+		c.tries++
+		c.statements(n.Body.Statements, newBlockScope(sc))
+		c.tries--
+		catch := newBlockScope(sc)
+		if n.Name != nil {
+			catch.names[n.Name.Value] = &symbol{typ: Map}
+		}
+		c.hoist(n.Catch.Statements, catch, false)
+		c.statements(n.Catch.Statements, catch)
+		return Dynamic
+
+	case *ast.ErrorExpression:
+		c.expr(n.Message, sc)
+		return Dynamic
+
+	case *ast.YieldExpression:
+		c.expr(n.Value, sc)
+		return Null
+
+	case *ast.RangeExpression:
+		for _, bound := range []ast.Expr{n.Start, n.End, n.Step} {
+			if bound == nil {
+				continue
+			}
+			if t := c.expr(bound, sc); c.known(t) && t != Int {
+				c.errorAt(n.Token, "range bounds must be int, got %s", t)
+			}
+		}
+		return Range
+
+	case *ast.ArrayComprehension:
+		clause := c.clause(n.Clause, sc)
+		c.expr(n.Element, clause)
+		return Array
+
+	case *ast.MapComprehension:
+		clause := c.clause(n.Clause, sc)
+		c.expr(n.Key, clause)
+		c.expr(n.Value, clause)
+		return Map
+	}
+	return Dynamic
+}
+
+func (c *checker) prefix(op string, right Type, tok token.Token) Type {
+	switch {
+	case op == "!":
+		return Bool
+	case op == "-" && numeric(right), op == "~" && right == Int:
+		return right
+	case c.known(right):
+		c.errorAt(tok, "unknown operator: %s%s", op, right)
+	}
+	return Dynamic
+}
+
+// infix works out the type of left op right the way the evaluator
+// would. Operators on objects may be overloaded, so they are dynamic.
+func (c *checker) infix(op string, left, right Type, tok token.Token) Type {
+	if !c.known(left) || !c.known(right) {
+		return Dynamic
+	}
+	switch {
+	case op == "*" && (left == String && right == Int || left == Int && right == String):
+		return String
+	case op == "+" && (left == String) != (right == String):
+		c.errorAt(tok, "type mismatch: %s + %s; convert with str() to concatenate", left, right)
+		return Dynamic
+	case (op == "==" || op == "!=") && (left == Null || right == Null):
+		return Bool
+	case numeric(left) && numeric(right) && (left == Float || right == Float):
+		return c.operator(op, left, right, Float, tok, "+", "-", "*", "/", "%", "**")
+	case (op == "==" || op == "!=") && c.enums[string(left)] != nil && c.enums[string(right)] != nil:
+		return Bool
+	case left != right:
+		c.errorAt(tok, "type mismatch: %s %s %s", left, op, right)
+		return Dynamic
+	case left == Int:
+		return c.operator(op, left, right, Int, tok, "+", "-", "*", "/", "%", "**", "&", "|", "^", "<<", ">>")
+	case left == String:
+		return c.operator(op, left, right, String, tok, "+")
+	case c.enums[string(left)] != nil:
+		return c.operator(op, left, right, left, tok)
+	case op == "==" || op == "!=":
+		return Bool
+	}
+	c.errorAt(tok, "unknown operator: %s %s %s", left, op, right)
+	return Dynamic
+}
+
+// operator gives result for any of ops, and bool for a comparison.
+func (c *checker) operator(op string, left, right, result Type, tok token.Token, ops ...string) Type {
+	switch op {
+	case "<", ">", "<=", ">=", "==", "!=":
+		return Bool
+	}
+	for _, o := range ops {
+		if o == op {
+			return result
+		}
+	}
+	c.errorAt(tok, "unknown operator: %s %s %s", left, op, right)
+	return Dynamic
+}
+
+func (c *checker) in(left, right Type, tok token.Token) Type {
+	if !c.known(left) || !c.known(right) {
+		return Bool
+	}
+	switch {
+	case right == String && left != String, right == Range && left != Int:
+		c.errorAt(tok, "type mismatch: %s in %s", left, right)
+	case right != String && right != Range:
+		c.errorAt(tok, "unknown operator: %s in %s", left, right)
+	}
+	return Bool
+}
+
+func (c *checker) index(n *ast.IndexExpression, sc *scope) Type {
+	left := c.expr(n.Left, sc)
+	index := c.expr(n.Index, sc)
+	if n.Optional && left == Null {
+		return Null
+	}
+	switch left {
+	case Map:
+		return Dynamic
+	case Array, String, Range:
+		if c.known(index) && index != Int {
+			c.errorAt(n.Token, "index operator not supported: %s", left)
+			return Dynamic
+		}
+		if left == Array {
+			return Dynamic
+		}
+		if left == Range {
+			return Int
+		}
+		return String
+	}
+	if c.known(left) {
+		c.errorAt(n.Token, "index operator not supported: %s", left)
+	}
+	return Dynamic
+}
+
+func (c *checker) slice(n *ast.SliceExpression, sc *scope) Type {
+	left := c.expr(n.Left, sc)
+	for _, bound := range []ast.Expr{n.Low, n.High} {
+		if bound == nil {
+			continue
+		}
+		if t := c.expr(bound, sc); c.known(t) && t != Int {
+			c.errorAt(n.Token, "slice bound must be int, got %s", t)
+		}
+	}
+	switch {
+	case n.Optional && left == Null:
+		return Null
+	case left == Array || left == String:
+		return left
+	case c.known(left):
+		c.errorAt(n.Token, "slice operator not supported: %s", left)
+	}
+	return Dynamic
+}
+
+// member checks n, returning the type of its value and, when it is a
+// method whose signature is known, the signature. call is set when n is
+// the method of a call.
+func (c *checker) member(n *ast.MemberExpression, sc *scope, call bool) (Type, *signature) {
+	name := n.Name.Value
+	if sym := c.symbolOf(n.Left, sc); sym != nil {
+		switch {
+		case sym.enum != nil:
+			if !sym.enum.members[name] {
+				c.errorAt(n.Token, "%s has no member %s", sym.enum.name, name)
+				return Dynamic, nil
+			}
+			return Type(sym.enum.name), nil
+		case sym.descriptor != nil && sym.view:
+			t, ok := sym.descriptor.declares(name)
+			if !ok {
+				c.errorAt(n.Token, "%s does not describe %s", sym.descriptor.name, name)
+			}
+			return t, sym.descriptor.method(name)
+		case sym.descriptor != nil:
+			t, ok := sym.descriptor.constants[name]
+			if !ok {
+				c.errorAt(n.Token, "%s has no constant %s", sym.descriptor.name, name)
+			}
+			return t, nil
+		}
+	}
+
+	left := c.expr(n.Left, sc)
+	if n.Optional && left == Null {
+		return Null, nil
+	}
+	if o := c.objects[string(left)]; o != nil {
+		if t, ok := o.props[name]; ok {
+			return t, nil
+		}
+		if t, ok := o.constants[name]; ok {
+			return t, nil
+		}
+		if sig, ok := o.methods[name]; ok {
+			return Method, sig
+		}
+		if call {
+			c.errorAt(n.Token, "undefined method: %s.%s", o.name, name)
+			return Dynamic, nil
+		}
+		c.errorAt(n.Token, "%s has no property %s", o.name, name)
+		return Dynamic, nil
+	}
+	if d := c.descriptors[string(left)]; d != nil {
+		// The value is an object described by d, which may have more
+		// members than d declares
+		t, _ := d.declares(name)
+		return t, d.method(name)
+	}
+	switch {
+	case left == Map:
+		return Dynamic, nil
+	case c.enums[string(left)] != nil && name == "Name":
+		return String, nil
+	case c.enums[string(left)] != nil && name == "Ordinal":
+		return Int, nil
+	case c.known(left):
+		c.errorAt(n.Token, "cannot read %s of %s", name, left)
+	}
+	return Dynamic, nil
+}
+
+// call checks a call's arguments against what it calls, and returns the
+// type of its result.
+func (c *checker) call(n *ast.CallExpression, sc *scope) Type {
+	var sig *signature
+	var called Type
+	switch fn := n.Function.(type) {
+	case *ast.Ident:
+		sym, ok := sc.lookup(fn.Value)
+		switch {
+		case ok && sym.object != nil:
+			o := sym.object
+			sig = &signature{name: o.name, params: o.params, returns: Type(o.name)}
+		case ok:
+			sig, called = sym.sig, sym.typ
+		case builtins[fn.Value] != nil:
+			sig = builtins[fn.Value]
+		default:
+			c.errorAt(fn.Token, "undefined method: %s", fn.Value)
+		}
+	case *ast.MemberExpression:
+		called, sig = c.member(fn, sc, true)
+	case *ast.FuncLiteral:
+		called = c.expr(fn, sc)
+		sig = newSignature(fn)
+	default:
+		called = c.expr(fn, sc)
+	}
+
+	args := make([]Type, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = c.expr(arg, sc)
+	}
+	if sig == nil {
+		if c.known(called) {
+			c.errorAt(n.Token, "not a function: %s", called)
+		}
+		return Dynamic
+	}
+	c.arguments(n, sig, args)
+	if sig.generator {
+		return Generator
+	}
+	return sig.returns
+}
+
+// arguments checks the arguments of call, whose types are args, against
+// sig, binding them to parameters the way the evaluator does.
+func (c *checker) arguments(call *ast.CallExpression, sig *signature, args []Type) {
+	for _, arg := range call.Args {
+		if _, ok := arg.(*ast.SpreadExpression); ok {
+			return
+		}
+	}
+	if builtins[sig.name] == sig {
+		for _, name := range call.Names {
+			if name != nil {
+				c.errorAt(call.Token, "built-in methods do not take named arguments, got %s", name.Value)
+				return
+			}
+		}
+	}
+	if sig.variadic {
+		return
+	}
+
+	// bound holds the index of the argument given for each parameter,
+	// or -1
+	bound := make([]int, len(sig.params))
+	for i := range bound {
+		bound[i] = -1
+	}
+	named := false
+	for i := range call.Args {
+		if i >= len(call.Names) || call.Names[i] == nil {
+			if i >= len(sig.params) {
+				c.wrongArgumentCount(call, sig)
+				return
+			}
+			bound[i] = i
+			continue
+		}
+
+		named = true
+		name := call.Names[i].Value
+		idx := sig.index(name)
+		if idx < 0 {
+			c.errorAt(call.Token, "%s has no parameter %s", sig.name, name)
+			return
+		}
+		if bound[idx] >= 0 {
+			c.errorAt(call.Token, "argument %s given more than once", name)
+			return
+		}
+		bound[idx] = i
+	}
+	for i, p := range sig.params {
+		if bound[i] >= 0 || p.optional {
+			continue
+		}
+		if named {
+			c.errorAt(call.Token, "missing argument for %s.%s", sig.name, p.name)
+		} else {
+			c.wrongArgumentCount(call, sig)
+		}
+		return
+	}
+
+	for i, p := range sig.params {
+		if bound[i] >= 0 && !c.assignable(p.typ, args[bound[i]]) {
+			c.errorAt(call.Token, "argument %d to %s: want %s, got %s", i+1, sig.name, p.typ, args[bound[i]])
+		}
+	}
+}
+
+func (c *checker) wrongArgumentCount(call *ast.CallExpression, sig *signature) {
+	required := 0
+	for _, p := range sig.params {
+		if !p.optional {
+			required++
+		}
+	}
+	if required == len(sig.params) {
+		c.errorAt(call.Token, "wrong number of arguments to %s: want %d, got %d", sig.name, required, len(call.Args))
+		return
+	}
+	c.errorAt(call.Token, "wrong number of arguments to %s: want %d to %d, got %d",
+		sig.name, required, len(sig.params), len(call.Args))
+}
+
+// clause declares the names a comprehension's clause binds in a new
+// block scope, which the rest of the comprehension is checked in.
+func (c *checker) clause(clause *ast.ComprehensionClause, sc *scope) *scope {
+	it := c.expr(clause.Iterable, sc)
+	inner := newBlockScope(sc)
+	if clause.Key != nil {
+		inner.names[clause.Key.Value] = &symbol{}
+	}
+	for _, name := range ast.TargetNames(clause.Value) {
+		inner.names[name.Value] = &symbol{}
+	}
+	if ident, ok := clause.Value.(*ast.Ident); ok && it == Range {
+		inner.names[ident.Value].typ = Int
+	}
+	if clause.Condition != nil {
+		c.expr(clause.Condition, inner)
+	}
+	return inner
+}
+
+// pattern declares the names a match arm's pattern binds.
+func (c *checker) pattern(p ast.Pattern, sc *scope) {
+	switch p := p.(type) {
+	case *ast.BindingPattern:
+		sc.names[p.Name.Value] = &symbol{}
+	case *ast.ValuePattern:
+		c.expr(p.Value, sc)
+	case *ast.TypePattern:
+		c.annotation(p.TypeName)
+		if p.Name.Value != "_" {
+			sc.names[p.Name.Value] = &symbol{typ: typeOf(p.TypeName)}
+		}
+	case *ast.ArrayPattern:
+		for _, el := range p.Elements {
+			c.pattern(el, sc)
+		}
+		if p.Rest != nil {
+			sc.names[p.Rest.Value] = &symbol{typ: Array}
+		}
+	case *ast.HashPattern:
+		for _, value := range p.Values {
+			c.pattern(value, sc)
+		}
+	}
+}
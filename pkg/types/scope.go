@@ -0,0 +1,93 @@
+package types
+
+// scope mirrors an evaluator environment. Method bodies and the program
+// get a scope of their own, and loop bodies, match arms and catch blocks
+// get a block scope, like object.NewBlockEnvironment.
+type scope struct {
+	names map[string]*symbol
+	outer *scope
+	block bool
+	// open is set on the scope of an instance whose members are not all
+	// known, such as the instance a descriptor's methods run in
+	open bool
+}
+
+// symbol is what the checker knows about a name.
+type symbol struct {
+	typ        Type
+	sig        *signature
+	object     *object
+	descriptor *descriptor
+	// view is set when descriptor is bound to an instance's view of it
+	view bool
+	enum *enum
+	// fixed is set for declarations, which stay the same however late
+	// a method that refers to them runs
+	fixed bool
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: make(map[string]*symbol), outer: outer}
+}
+
+func newBlockScope(outer *scope) *scope {
+	s := newScope(outer)
+	s.block = true
+	return s
+}
+
+// lookup finds the symbol that name refers to. A variable declared
+// outside the method being checked may have changed by the time the
+// method runs, so only fixed symbols are trusted beyond it. Inside an
+// open scope a name that can't be found may be a member, so it is
+// dynamic rather than missing.
+func (s *scope) lookup(name string) (*symbol, bool) {
+	crossed, open := false, false
+	for sc := s; sc != nil; sc = sc.outer {
+		if sym, ok := sc.names[name]; ok {
+			if crossed && !sym.fixed || open && sym.descriptor != nil {
+				return &symbol{}, true
+			}
+			return sym, true
+		}
+		open = open || sc.open
+		crossed = crossed || !sc.block
+	}
+	if open {
+		return &symbol{}, true
+	}
+	return nil, false
+}
+
+// assign records that name was assigned sym, the way Environment.Assign
+// would. Assigning to a variable outside a block only sometimes
+// happens, so the variable keeps its type only if sym agrees with it.
+func (s *scope) assign(name string, sym *symbol) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if prev, ok := sc.names[name]; ok {
+			if sc != s && (prev.typ != sym.typ || prev.sig != sym.sig) {
+				sym = &symbol{}
+			}
+			sc.names[name] = sym
+			return
+		}
+		if !sc.block {
+			break
+		}
+	}
+	s.names[name] = sym
+}
+
+// declare adds name to s, unless assigning to name in s would update a
+// variable that is already declared.
+func (s *scope) declare(name string, sym *symbol) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if _, ok := sc.names[name]; ok {
+			return
+		}
+		if !sc.block {
+			break
+		}
+	}
+	s.names[name] = sym
+}
@@ -0,0 +1,159 @@
+// Package types checks Jet programs for type errors before they run.
+// Types are known where annotations or literals make them so, and the
+// rest of a program is treated as dynamic, so the checker only reports
+// problems that would fail at runtime.
+package types
+
+// Type names the type of a value, using the names type annotations use.
+// An object, descriptor or enum's type is its name.
+type Type string
+
+const (
+	// Dynamic is the type of a value the checker can't know.
+	Dynamic   Type = ""
+	Int       Type = "int"
+	Float     Type = "float"
+	String    Type = "string"
+	Bool      Type = "bool"
+	Array     Type = "array"
+	Map       Type = "map"
+	Range     Type = "range"
+	Null      Type = "null"
+	Method    Type = "meth"
+	Generator Type = "generator"
+)
+
+// builtinTypes maps the builtin names usable in annotations to their
+// types.
+var builtinTypes = map[string]Type{
+	"int":       Int,
+	"float":     Float,
+	"string":    String,
+	"bool":      Bool,
+	"boolean":   Bool,
+	"array":     Array,
+	"map":       Map,
+	"range":     Range,
+	"generator": Generator,
+	"meth":      Method,
+	"null":      Null,
+}
+
+// primitive reports whether t is a builtin type whose operators the
+// checker knows, as opposed to one that might be overloaded.
+func primitive(t Type) bool {
+	switch t {
+	case Int, Float, String, Bool, Null, Array, Map, Range:
+		return true
+	}
+	return false
+}
+
+func numeric(t Type) bool {
+	return t == Int || t == Float
+}
+
+// signature is what the checker knows about a method's parameters and
+// result.
+type signature struct {
+	name    string
+	params  []param
+	returns Type
+	// variadic is set for builtins that take any number of arguments
+	variadic bool
+	// generator is set when the method's body yields
+	generator bool
+}
+
+type param struct {
+	name     string
+	typ      Type
+	optional bool
+}
+
+// builtins describes the evaluator's builtin methods. Keep it in step
+// with the evaluator's own table.
+var builtins = map[string]*signature{
+	"puts":        {name: "puts", variadic: true},
+	"print":       {name: "print", variadic: true},
+	"len":         {name: "len", params: []param{{name: "value"}}, returns: Int},
+	"array":       {name: "array", params: []param{{name: "value"}}, returns: Array},
+	"next":        {name: "next", params: []param{{name: "iterator"}}},
+	"close":       {name: "close", params: []param{{name: "iterator"}}, returns: Null},
+	"str":         {name: "str", params: []param{{name: "value"}}, returns: String},
+	"first":       {name: "first", params: []param{{name: "array", typ: Array}}},
+	"tail":        {name: "tail", params: []param{{name: "array", typ: Array}}},
+	"append":      {name: "append", params: []param{{name: "array", typ: Array}, {name: "value"}}, returns: Array},
+	"sort":        {name: "sort", params: []param{{name: "array", typ: Array}}, returns: Array},
+	"descriptors": {name: "descriptors", params: []param{{name: "object"}}, returns: Array},
+}
+
+// descriptor is what the checker knows about a descriptor.
+type descriptor struct {
+	name   string
+	params []param
+	// props holds the type of every property, including parameters
+	props     map[string]Type
+	constants map[string]Type
+	methods   map[string]*signature
+	// required holds the methods the descriptor requires, which the
+	// objects it describes provide
+	required map[string]*signature
+}
+
+// declares reports the type of d's member name, and whether it has one.
+func (d *descriptor) declares(name string) (Type, bool) {
+	if t, ok := d.props[name]; ok {
+		return t, true
+	}
+	if t, ok := d.constants[name]; ok {
+		return t, true
+	}
+	if d.method(name) != nil {
+		return Method, true
+	}
+	return Dynamic, false
+}
+
+// method returns the signature of d's method name, including the
+// methods it requires, or nil if it has none.
+func (d *descriptor) method(name string) *signature {
+	if sig, ok := d.methods[name]; ok {
+		return sig
+	}
+	return d.required[name]
+}
+
+// object is what the checker knows about an object definition.
+type object struct {
+	name        string
+	descriptors []*descriptor
+	params      []param
+	props       map[string]Type
+	constants   map[string]Type
+	methods     map[string]*signature
+}
+
+func (o *object) describedBy(name string) bool {
+	for _, d := range o.descriptors {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type enum struct {
+	name    string
+	members map[string]bool
+}
+
+// index returns the position of sig's parameter name, or -1.
+func (sig *signature) index(name string) int {
+	for i, p := range sig.params {
+		if p.name == name {
+			return i
+		}
+	}
+	return -1
+}
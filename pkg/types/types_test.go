@@ -0,0 +1,146 @@
+package types
+
+import (
+	"github.com/alexjwhite-cb/jet/pkg/lexer"
+	"github.com/alexjwhite-cb/jet/pkg/parser"
+	"strings"
+	"testing"
+)
+
+func testCheck(t *testing.T, input string) (errors, warnings []string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return Check(program)
+}
+
+const vehicles = `describe Vehicle: Seats int {
+	Wheels int = 4
+	const Material = "Metal"
+	meth summary { "{Seats} seats" }
+}
+describe Jet: Name string, TopSpeed int {
+	meth boost: by int -> int { TopSpeed * by }
+}
+object Plane: Vehicle, Jet {
+	meth land { Vehicle.Wheels }
+}
+`
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// Programs that can't fail the checks run cleanly
+		{"x = 1\ny = x + 2\nputs(y * 1.5)", nil},
+		{"meth add: x, y { x + y }\nadd(1, \"a\")", nil},
+		{"meth twice: f { f(f(1)) }\ntwice(meth: x { x + 1 })", nil},
+		{"later()\nmeth later { 1 }", nil},
+		{"meth f { g() }\nmeth g { 1 }", nil},
+		{"if true { f = meth { 1 } } else { f = meth { 2 } }\nf()", nil},
+		{"x = 1\nif true { x = \"a\" }\nx + 1", nil},
+		{"x = 1\nfor i in 1..3 { x = \"a\" }\nx + 1", nil},
+		{"m = {\"a\": 1}\nm.a + m[\"b\"]", nil},
+		{"x = null\nx ?? 1", nil},
+		{"meth f: xs { [a, b] = xs; a + b }", nil},
+		{"match 3 { n int -> n + 1, s string -> s + \"!\", _ -> 0 }", nil},
+		{"try { error(\"no\") } catch err { err[\"message\"] + \"!\" }", nil},
+		{"object Money { Amount int = 0\n overload +: o { Money() } }\nMoney() + 1", nil},
+		{vehicles + "p = Plane(2, \"Falcon\", 100)\np.boost(2) + p.Wheels\np.Name + \"!\"", nil},
+		{vehicles + "meth fly: j Jet { j.boost(2) }\nfly(Plane(2, \"Falcon\", 100))", nil},
+
+		// Mismatched types
+		{"1 + \"a\"", []string{"line 1, col 3: type mismatch: int + string; convert with str() to concatenate"}},
+		{"x = 1.5\nx % true", []string{"line 2, col 3: type mismatch: float % bool"}},
+		{"\"a\" - \"b\"", []string{"line 1, col 5: unknown operator: string - string"}},
+		{"-\"a\"", []string{"line 1, col 1: unknown operator: -string"}},
+		{"x = \"a\"\nx.size", []string{"line 2, col 2: cannot read size of string"}},
+		{"for i in 1..3 { puts(i + \"!\") }", []string{"line 1, col 24: type mismatch: int + string; convert with str() to concatenate"}},
+		{"meth half: n int -> int { n / 2.0 }", []string{"line 1, col 27: return value of half: want int, got float"}},
+		{"meth f -> int { (\"a\")-> }", []string{"line 1, col 22: return value of f: want int, got string"}},
+		{"meth f -> int { }", []string{"line 1, col 15: return value of f: want int, got null"}},
+		{"meth f -> int { yield 1 }", []string{"line 1, col 11: return value of f: want int, got generator"}},
+		{"meth f: x number { x }", []string{"line 1, col 11: unknown type: number"}},
+		{"1 is number", []string{"line 1, col 6: unknown type: number"}},
+		{"meth f: a null -> null { a }\nf(null)", nil},
+		{"meth f: a null { a }\nf(1)", []string{"line 2, col 2: argument 1 to f: want null, got int"}},
+
+		// Calls
+		{"nope(1)", []string{"line 1, col 1: undefined method: nope"}},
+		{"meth f { nope() }", []string{"line 1, col 10: undefined method: nope"}},
+		{"5(1)", []string{"line 1, col 2: not a function: int"}},
+		{"meth area: w int, h int -> int { w * h }\narea(2, \"3\")", []string{"line 2, col 5: argument 2 to area: want int, got string"}},
+		{"meth area: w int, h int -> int { w * h }\narea(2)", []string{"line 2, col 5: wrong number of arguments to area: want 2, got 1"}},
+		{"meth area: w int, h int -> int { w * h }\narea(h: 2, w: 1.5)", []string{"line 2, col 5: argument 1 to area: want int, got float"}},
+		{"meth area: w int, h int -> int { w * h }\narea(w: 2)", []string{"line 2, col 5: missing argument for area.h"}},
+		{"meth area: w int, h int -> int { w * h }\narea(2, d: 3)", []string{"line 2, col 5: area has no parameter d"}},
+		{"meth f: a, b = 1 { a }\nf()", []string{"line 2, col 2: wrong number of arguments to f: want 1 to 2, got 0"}},
+		{"meth f: a int = \"x\" { a }", []string{"line 1, col 9: argument 1 to f: want int, got string"}},
+		{"meth f -> int { 1 }\nf() + \"a\"", []string{"line 2, col 5: type mismatch: int + string; convert with str() to concatenate"}},
+		{"len(\"a\", \"b\")", []string{"line 1, col 4: wrong number of arguments to len: want 1, got 2"}},
+		{"first(5)", []string{"line 1, col 6: argument 1 to first: want array, got int"}},
+		{"len(x: \"abc\")", []string{"line 1, col 4: built-in methods do not take named arguments, got x"}},
+		{"meth f: a int { a }\nf(...[1])", nil},
+
+		// Descriptors, objects and enums
+		{vehicles + "Plane(2, \"Falcon\")", []string{"line 12, col 6: wrong number of arguments to Plane: want 3, got 2"}},
+		{vehicles + "Plane(\"2\", \"Falcon\", 100)", []string{"line 12, col 6: argument 1 to Plane: want int, got string"}},
+		{vehicles + "p = Plane(2, \"Falcon\", 100)\np.Fuel", []string{"line 13, col 2: Plane has no property Fuel"}},
+		{vehicles + "p = Plane(2, \"Falcon\", 100)\np.fly(2)", []string{"line 13, col 2: undefined method: Plane.fly"}},
+		{vehicles + "p = Plane(2, \"Falcon\", 100)\np.Wheels = \"four\"", []string{"line 13, col 10: property Wheels of Plane: want int, got string"}},
+		{vehicles + "p = Plane(2, \"Falcon\", 100)\np.Material = \"Wood\"", []string{"line 13, col 12: cannot assign to constant Material"}},
+		{vehicles + "p = Plane(2, \"Falcon\", 100)\np.boost(\"2\")", []string{"line 13, col 8: argument 1 to boost: want int, got string"}},
+		{vehicles + "Vehicle.Colour", []string{"line 12, col 8: Vehicle has no constant Colour"}},
+		{vehicles + "meth fly: v Vehicle { v }\nfly(5)", []string{"line 13, col 4: argument 1 to fly: want Vehicle, got int"}},
+		{"describe D { X int = \"x\" }", []string{"line 1, col 14: property X of D: want int, got string"}},
+		{"describe D: A { meth f { D.B } }", []string{"line 1, col 27: D does not describe B"}},
+		{"object O: Nope {}", []string{"line 1, col 11: Nope is not a descriptor"}},
+		{"enum Colour { Red, Green }\nColour.Blue", []string{"line 2, col 7: Colour has no member Blue"}},
+		{"enum Colour { Red, Green }\nColour.Red.Name + 1", []string{"line 2, col 17: type mismatch: string + int; convert with str() to concatenate"}},
+		{"enum Colour { Red }\nenum Size { Small }\nColour.Red < Size.Small", []string{"line 3, col 12: type mismatch: Colour < Size"}},
+		{"enum Colour { Red }\nenum Size { Small }\nColour.Red == Size.Small", nil},
+	}
+
+	for _, tt := range tests {
+		errors, _ := testCheck(t, tt.input)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong errors for %q: want %q, got %q", tt.input, tt.expected, errors)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if errors[i] != msg {
+				t.Errorf("wrong error for %q: want %q, got %q", tt.input, msg, errors[i])
+			}
+		}
+	}
+}
+
+func TestCheckInTry(t *testing.T) {
+	tests := []struct {
+		input    string
+		errors   []string
+		warnings []string
+	}{
+		{"try { 1 + \"a\" } catch err { err.message }", nil,
+			[]string{"line 1, col 9: type mismatch: int + string; convert with str() to concatenate"}},
+		{"try { try { -\"a\" } catch { 0 } } catch { 1 + true }",
+			[]string{"line 1, col 44: type mismatch: int + bool"},
+			[]string{"line 1, col 13: unknown operator: -string"}},
+		{"try { meth f { 1 + \"a\" } } catch { 0 }",
+			[]string{"line 1, col 18: type mismatch: int + string; convert with str() to concatenate"}, nil},
+	}
+
+	for _, tt := range tests {
+		errors, warnings := testCheck(t, tt.input)
+		if strings.Join(errors, "\n") != strings.Join(tt.errors, "\n") {
+			t.Errorf("wrong errors for %q: want %q, got %q", tt.input, tt.errors, errors)
+		}
+		if strings.Join(warnings, "\n") != strings.Join(tt.warnings, "\n") {
+			t.Errorf("wrong warnings for %q: want %q, got %q", tt.input, tt.warnings, warnings)
+		}
+	}
+}